@@ -0,0 +1,257 @@
+package connect4
+
+import (
+	"math/bits"
+	"sync"
+)
+
+/* Bitboard layout: each column uses Height+1 bits, bottom row first, with an
+ * always-empty sentinel bit on top so that shifts never carry a line from one
+ * column into the next. For the standard 7x6 board:
+ *
+ *  6 13 20 27 34 41 48
+ *  5 12 19 26 33 40 47
+ *  4 11 18 25 32 39 46
+ *  3 10 17 24 31 38 45
+ *  2  9 16 23 30 37 44
+ *  1  8 15 22 29 36 43
+ *  0  7 14 21 28 35 42
+ *
+ * Two words give room for boards up to 128 bits including the sentinel row.
+ */
+
+const maxBitboardBits = 128
+
+type bitboard struct {
+	lo uint64
+	hi uint64
+}
+
+func singleBit(index uint) bitboard {
+	return bitboard{1, 0}.shl(index)
+}
+
+func (b bitboard) and(other bitboard) bitboard {
+	return bitboard{b.lo & other.lo, b.hi & other.hi}
+}
+
+func (b bitboard) or(other bitboard) bitboard {
+	return bitboard{b.lo | other.lo, b.hi | other.hi}
+}
+
+func (b bitboard) xor(other bitboard) bitboard {
+	return bitboard{b.lo ^ other.lo, b.hi ^ other.hi}
+}
+
+func (b bitboard) andNot(other bitboard) bitboard {
+	return bitboard{b.lo &^ other.lo, b.hi &^ other.hi}
+}
+
+func (b bitboard) add(other bitboard) bitboard {
+	lo, carry := bits.Add64(b.lo, other.lo, 0)
+	hi, _ := bits.Add64(b.hi, other.hi, carry)
+	return bitboard{lo, hi}
+}
+
+func (b bitboard) shl(n uint) bitboard {
+	if n >= 64 {
+		return bitboard{0, b.lo << (n - 64)}
+	}
+	return bitboard{b.lo << n, b.hi<<n | b.lo>>(64-n)}
+}
+
+func (b bitboard) shr(n uint) bitboard {
+	if n >= 64 {
+		return bitboard{b.hi >> (n - 64), 0}
+	}
+	return bitboard{b.lo>>n | b.hi<<(64-n), b.hi >> n}
+}
+
+func (b bitboard) isZero() bool {
+	return b.lo == 0 && b.hi == 0
+}
+
+func (b bitboard) intersects(other bitboard) bool {
+	return b.lo&other.lo != 0 || b.hi&other.hi != 0
+}
+
+func (b bitboard) count() int {
+	return bits.OnesCount64(b.lo) + bits.OnesCount64(b.hi)
+}
+
+// layout holds everything about the bitboard geometry that depends only on
+// the Rules, so it is computed once and shared by every game using them.
+type layout struct {
+	rules        Rules
+	columnStride uint
+	bottomMask   bitboard
+	boardMask    bitboard
+	lineShifts   [4]uint
+	windows      []bitboard
+
+	zobristPieces [][]uint64
+	zobristSide   uint64
+	// zobristTurns is keyed by the player to move less one, for MultiGameState
+	zobristTurns   []uint64
+	zobristNeutral []uint64
+}
+
+func newLayout(rules Rules) *layout {
+	layout := &layout{
+		rules:        rules,
+		columnStride: uint(rules.Height + 1),
+	}
+
+	// Vertical, horizontal, diagonally down and diagonally up neighbours
+	layout.lineShifts = [4]uint{1, layout.columnStride, layout.columnStride - 1, layout.columnStride + 1}
+
+	for x := 0; x < rules.Width; x++ {
+		layout.bottomMask = layout.bottomMask.or(layout.cellMask(x, 0))
+		layout.boardMask = layout.boardMask.or(layout.columnMask(x))
+	}
+
+	directions := [][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}}
+	for _, direction := range directions {
+		// On a cylinder lines can wrap around, as long as they don't meet themselves
+		wraps := rules.Cylinder && direction[0] != 0 && (direction[1] != 0 || rules.ConnectLength <= rules.Width)
+
+		// A horizontal line right round the cylinder is the same from every start
+		startWidth := rules.Width
+		if wraps && direction[1] == 0 && rules.ConnectLength == rules.Width {
+			startWidth = 1
+		}
+
+		for x := 0; x < startWidth; x++ {
+			for row := 0; row < rules.Height; row++ {
+				endX := x + (rules.ConnectLength-1)*direction[0]
+				endRow := row + (rules.ConnectLength-1)*direction[1]
+				if (!wraps && (endX < 0 || endX >= rules.Width)) || endRow < 0 || endRow >= rules.Height {
+					continue
+				}
+
+				var window bitboard
+				for i := 0; i < rules.ConnectLength; i++ {
+					window = window.or(layout.cellMask((x+i*direction[0])%rules.Width, row+i*direction[1]))
+				}
+				layout.windows = append(layout.windows, window)
+			}
+		}
+	}
+
+	layout.initZobrist()
+
+	return layout
+}
+
+var (
+	layoutsMutex sync.Mutex
+	layouts      = map[Rules]*layout{}
+)
+
+func getLayout(rules Rules) *layout {
+	layoutsMutex.Lock()
+	defer layoutsMutex.Unlock()
+
+	layout, ok := layouts[rules]
+	if !ok {
+		layout = newLayout(rules)
+		layouts[rules] = layout
+	}
+
+	return layout
+}
+
+// cellMask returns the bit for column x, counting rows up from the bottom.
+func (layout *layout) cellMask(x int, row int) bitboard {
+	return singleBit(uint(x)*layout.columnStride + uint(row))
+}
+
+func (layout *layout) columnMask(x int) bitboard {
+	var column bitboard
+	for row := 0; row < layout.rules.Height; row++ {
+		column = column.or(layout.cellMask(x, row))
+	}
+
+	return column
+}
+
+func (layout *layout) topMask(x int) bitboard {
+	return layout.cellMask(x, layout.rules.Height-1)
+}
+
+func (layout *layout) playableCells(mask bitboard) bitboard {
+	return mask.add(layout.bottomMask).and(layout.boardMask)
+}
+
+func (layout *layout) hasConnect(position bitboard) bool {
+	// Shifts can't follow a line around a cylinder, so check every window
+	if layout.rules.Cylinder {
+		for _, window := range layout.windows {
+			if position.and(window) == window {
+				return true
+			}
+		}
+		return false
+	}
+
+	connectLength := uint(layout.rules.ConnectLength)
+
+	for _, shift := range layout.lineShifts {
+		// Double the run length each step, then top up with an overlapping shift
+		run := position
+		covered := uint(1)
+		for covered*2 <= connectLength {
+			run = run.and(run.shr(covered * shift))
+			covered *= 2
+		}
+		if covered < connectLength {
+			run = run.and(run.shr((connectLength - covered) * shift))
+		}
+
+		if !run.isZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// winningCells returns the empty cells that would complete a line for
+// position, whether or not they can be played yet.
+func (layout *layout) winningCells(position bitboard, mask bitboard) bitboard {
+	connectLength := layout.rules.ConnectLength
+	var cells bitboard
+
+	if layout.rules.Cylinder {
+		for _, window := range layout.windows {
+			missing := window.andNot(position)
+			if missing.count() == 1 {
+				cells = cells.or(missing)
+			}
+		}
+		return cells.and(layout.boardMask.andNot(mask))
+	}
+
+	// before[i] marks cells with i of the player's pieces directly before them, after[i] directly after.
+	// Usual connect lengths fit on the stack, which matters to the solver.
+	var beforeCells, afterCells [8]bitboard
+	before, after := beforeCells[:], afterCells[:]
+	if connectLength > len(beforeCells) {
+		before = make([]bitboard, connectLength)
+		after = make([]bitboard, connectLength)
+	}
+	for _, shift := range layout.lineShifts {
+		before[0] = layout.boardMask
+		after[0] = layout.boardMask
+		for i := 1; i < connectLength; i++ {
+			before[i] = before[i-1].and(position.shl(uint(i) * shift))
+			after[i] = after[i-1].and(position.shr(uint(i) * shift))
+		}
+
+		for i := 0; i < connectLength; i++ {
+			cells = cells.or(before[i].and(after[connectLength-1-i]))
+		}
+	}
+
+	return cells.and(layout.boardMask.andNot(mask))
+}
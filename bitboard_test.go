@@ -0,0 +1,33 @@
+package connect4
+
+import "testing"
+
+func TestLayoutWindowsAreDistinct(t *testing.T) {
+	for _, rules := range []Rules{
+		StandardRules,
+		{Width: 7, Height: 6, ConnectLength: 4, Cylinder: true},
+		{Width: 4, Height: 4, ConnectLength: 4, Cylinder: true},
+		{Width: 3, Height: 5, ConnectLength: 4, Cylinder: true},
+	} {
+		layout := getLayout(rules)
+		seen := make(map[bitboard]bool)
+		for _, window := range layout.windows {
+			if seen[window] {
+				t.Errorf("%s: window %v repeated", rules, window)
+			}
+			seen[window] = true
+		}
+	}
+
+	// Each row of a 4 wide cylinder has one ring round it
+	layout := getLayout(Rules{Width: 4, Height: 4, ConnectLength: 4, Cylinder: true})
+	horizontal := 0
+	for _, window := range layout.windows {
+		if window.and(layout.bottomMask).count() == 4 {
+			horizontal++
+		}
+	}
+	if horizontal != 1 {
+		t.Errorf("%d rings round the bottom row", horizontal)
+	}
+}
@@ -1,22 +1,77 @@
 package connect4
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Piece int
 
 const (
 	EmptyPiece Piece = iota
 	Player1Piece
 	Player2Piece
+	Player3Piece
+
+	// NeutralPiece is a blocker belonging to neither player, placed as part
+	// of a setup. It fills its cell and breaks any line through it.
+	NeutralPiece Piece = -1
 )
 
+// pieceSymbols renders each Piece, indexed by its value. Games with more than
+// three players carry on through the list.
+const pieceSymbols = " RYGBPO"
+
+const neutralSymbol = "#"
+
+func (piece Piece) symbol() string {
+	if piece == NeutralPiece {
+		return neutralSymbol
+	}
+
+	if piece < EmptyPiece || int(piece) >= len(pieceSymbols) {
+		return "?"
+	}
+
+	return pieceSymbols[piece : piece+1]
+}
+
+// Dimensions of the standard board, see StandardRules.
 const BoardHeight int = 6
 const BoardWidth int = 7
 
-type Board [BoardHeight][BoardWidth]Piece
+// Board is indexed [y][x] with y = 0 as the top row.
+type Board [][]Piece
+
+func NewBoard(width int, height int) *Board {
+	board := make(Board, height)
+	for y := range board {
+		board[y] = make([]Piece, width)
+	}
+
+	return &board
+}
 
-func (board *Board) IsEqual(otherBoard *Board) bool {
-	for y := 0; y < BoardHeight; y++ {
-		for x := 0; x < BoardWidth; x++ {
-			if board[y][x] != otherBoard[y][x] {
+func (board Board) Width() int {
+	if len(board) == 0 {
+		return 0
+	}
+
+	return len(board[0])
+}
+
+func (board Board) Height() int {
+	return len(board)
+}
+
+func (board Board) IsEqual(otherBoard *Board) bool {
+	if board.Width() != otherBoard.Width() || board.Height() != otherBoard.Height() {
+		return false
+	}
+
+	for y := 0; y < board.Height(); y++ {
+		for x := 0; x < board.Width(); x++ {
+			if board[y][x] != (*otherBoard)[y][x] {
 				return false
 			}
 		}
@@ -25,48 +80,57 @@ func (board *Board) IsEqual(otherBoard *Board) bool {
 	return true
 }
 
-func (board *Board) String() string {
+func (board Board) String() string {
+	return board.StringMarked(nil)
+}
+
+// StringMarked renders the board with the given cells, such as a winning line,
+// wrapped in '*'.
+func (board Board) StringMarked(markedCells []Cell) string {
+	marked := map[Cell]bool{}
+	for _, cell := range markedCells {
+		marked[cell] = true
+	}
+
+	separator := strings.Repeat("+---", board.Width()) + "+\n"
+
 	var output string
-	output += "+---+---+---+---+---+---+---+\n"
-	output += "| 0 | 1 | 2 | 3 | 4 | 5 | 6 |\n"
-	for y := 0; y < BoardHeight; y++ {
-		output += "+---+---+---+---+---+---+---+\n"
-		for x := 0; x < BoardWidth; x++ {
-			output += "| "
-			switch board[y][x] {
-			case EmptyPiece:
-				output += "  "
-			case Player1Piece:
-				output += "R "
-			case Player2Piece:
-				output += "Y "
-			default:
-				output += "? "
+	output += separator
+	for x := 0; x < board.Width(); x++ {
+		output += fmt.Sprintf("| %-2d", x)
+	}
+	output += "|\n"
+	for y := 0; y < board.Height(); y++ {
+		output += separator
+		for x := 0; x < board.Width(); x++ {
+			marker := " "
+			if marked[Cell{x, y}] {
+				marker = "*"
 			}
+
+			output += "|" + marker + board[y][x].symbol() + marker
 		}
 		output += "|\n"
 	}
-	output += "+---+---+---+---+---+---+---+\n"
+	output += separator
 
 	return output
 }
 
-func (board *Board) Print() {
+func (board Board) Print() {
 	print(board.String())
 }
 
-func (board *Board) Clone() *Board {
-	newBoard := &Board{}
+func (board Board) Clone() *Board {
+	newBoard := NewBoard(board.Width(), board.Height())
 
-	for y := 0; y < BoardHeight; y++ {
-		for x := 0; x < BoardWidth; x++ {
-			newBoard[y][x] = board[y][x]
-		}
+	for y := 0; y < board.Height(); y++ {
+		copy((*newBoard)[y], board[y])
 	}
 
 	return newBoard
 }
 
-func (board *Board) CloneGeneric() interface{} {
+func (board Board) CloneGeneric() interface{} {
 	return board.Clone()
 }
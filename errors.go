@@ -0,0 +1,41 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrGameOver               = errors.New("game is already over")
+	ErrColumnFull             = errors.New("column is full")
+	ErrColumnOutOfRange       = errors.New("column out of range")
+	ErrRowOutOfRange          = errors.New("row out of range")
+	ErrCellOccupied           = errors.New("cell is already occupied")
+	ErrCorruptState           = errors.New("corrupt game state")
+	ErrMoveNotAllowed         = errors.New("move type not allowed by the rules")
+	ErrCannotPop              = errors.New("bottom piece doesn't belong to the player")
+	ErrInvalidMoveString      = errors.New("unable to parse move")
+	ErrInvalidGameDescription = errors.New("invalid gameState description")
+	ErrInvalidRules           = errors.New("invalid rules")
+	ErrNothingToUndo          = errors.New("no move to undo")
+	ErrNothingToRedo          = errors.New("no move to redo")
+	ErrStaleSnapshot          = errors.New("game has changed since the snapshot")
+	ErrNotYourTurn            = errors.New("it is not the player's turn")
+	ErrUnsupportedGame        = errors.New("engine can't search this game")
+	ErrUnknownPlayer          = errors.New("no such player")
+)
+
+// MoveError reports why a move was rejected. Use errors.Is against the Err*
+// values above to find the reason, or errors.As to get the move itself.
+type MoveError struct {
+	Move Move
+	Err  error
+}
+
+func (err *MoveError) Error() string {
+	return fmt.Sprintf("invalid move %s: %s", err.Move, err.Err)
+}
+
+func (err *MoveError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,255 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type EventType int
+
+const (
+	MoveMadeEvent EventType = iota
+	MoveUndoneEvent
+	GameOverEvent
+	ResetEvent
+	SnapshotEvent
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case MoveMadeEvent:
+		return "move made"
+	case MoveUndoneEvent:
+		return "move undone"
+	case GameOverEvent:
+		return "game over"
+	case ResetEvent:
+		return "reset"
+	case SnapshotEvent:
+		return "snapshot"
+	default:
+		return "unknown event"
+	}
+}
+
+// Event describes a change to a GameState. Move and Player are the move and
+// the player who made it for MoveMadeEvent and MoveUndoneEvent, and the
+// resigning player for a GameOverEvent or MoveUndoneEvent with Resigned set.
+// Turn is always the turn after the change, so for GameOverEvent it is the
+// result. Snapshot is a copy of the game, set only for SnapshotEvent.
+type Event struct {
+	Type     EventType
+	Move     Move
+	Player   PlayerID
+	Turn     Turn
+	Resigned bool
+	Snapshot *GameState
+}
+
+type DeliveryPolicy int
+
+const (
+	// BlockingDelivery waits for the subscriber to take each event, once the
+	// buffer is full.
+	BlockingDelivery DeliveryPolicy = iota
+	// DropWhenFull discards events the subscriber has no room for, so a slow
+	// subscriber never holds up the game.
+	DropWhenFull
+)
+
+type SubscribeOptions struct {
+	Policy     DeliveryPolicy
+	BufferSize int
+	// Context ends the subscription when it is done.
+	Context context.Context
+	// Snapshot makes the first event a SnapshotEvent with the current game,
+	// for subscribers joining part way through.
+	Snapshot bool
+}
+
+type Subscription struct {
+	events  chan Event
+	policy  DeliveryPolicy
+	done    chan struct{}
+	once    sync.Once
+	mutex   sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// Events returns the channel events are delivered on. It is closed once the
+// subscription ends.
+func (subscription *Subscription) Events() <-chan Event {
+	return subscription.events
+}
+
+// Dropped returns how many events were discarded under DropWhenFull.
+func (subscription *Subscription) Dropped() int {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	return subscription.dropped
+}
+
+// Unsubscribe stops delivery and closes the Events channel. It is safe to call
+// more than once and from any goroutine.
+func (subscription *Subscription) Unsubscribe() {
+	subscription.once.Do(func() {
+		// Wake up any blocked delivery before taking the lock it holds
+		close(subscription.done)
+
+		subscription.mutex.Lock()
+		subscription.closed = true
+		close(subscription.events)
+		subscription.mutex.Unlock()
+	})
+}
+
+func (subscription *Subscription) isClosed() bool {
+	select {
+	case <-subscription.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (subscription *Subscription) deliver(event Event) {
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	if subscription.closed {
+		return
+	}
+
+	if subscription.policy == DropWhenFull {
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.dropped++
+		}
+		return
+	}
+
+	select {
+	case subscription.events <- event:
+	case <-subscription.done:
+	}
+}
+
+func (gameState *GameState) Subscribe(options SubscribeOptions) *Subscription {
+	bufferSize := options.BufferSize
+	if options.Snapshot {
+		bufferSize++
+	}
+
+	subscription := &Subscription{
+		events: make(chan Event, bufferSize),
+		policy: options.Policy,
+		done:   make(chan struct{}),
+	}
+
+	if options.Snapshot {
+		subscription.events <- Event{Type: SnapshotEvent, Turn: gameState.turn, Snapshot: gameState.Clone()}
+	}
+
+	if options.Context != nil {
+		go func() {
+			select {
+			case <-options.Context.Done():
+				subscription.Unsubscribe()
+			case <-subscription.done:
+			}
+		}()
+	}
+
+	gameState.subscriptions = append(gameState.subscriptions, subscription)
+
+	return subscription
+}
+
+func (gameState *GameState) publish(event Event) {
+	subscriptions := gameState.subscriptions[:0]
+	for _, subscription := range gameState.subscriptions {
+		if subscription.isClosed() {
+			continue
+		}
+
+		subscription.deliver(event)
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	// Forget unsubscribed entries so they can be collected
+	for i := len(subscriptions); i < len(gameState.subscriptions); i++ {
+		gameState.subscriptions[i] = nil
+	}
+	gameState.subscriptions = subscriptions
+}
+
+func (gameState *GameState) announceMove(move Move, player PlayerID) {
+	for _, moveListener := range gameState.moveListeners {
+		moveListener <- move
+	}
+
+	if gameState.IsGameOver() {
+		gameState.closeMoveListeners()
+	}
+
+	gameState.publish(Event{Type: MoveMadeEvent, Move: move, Player: player, Turn: gameState.turn})
+	if gameState.IsGameOver() {
+		gameState.publish(Event{Type: GameOverEvent, Turn: gameState.turn})
+	}
+}
+
+// closeMoveListeners closes the channels from RegisterMoveListener at the end
+// of the game.
+func (gameState *GameState) closeMoveListeners() {
+	for _, moveListener := range gameState.moveListeners {
+		close(moveListener)
+	}
+	gameState.moveListeners = nil
+}
+
+// Resign ends the game as a win for player's opponent. UndoMove withdraws the
+// resignation.
+func (gameState *GameState) Resign(player PlayerID) error {
+	if player != Player1 && player != Player2 {
+		return fmt.Errorf("%w: player %d", ErrUnknownPlayer, player)
+	}
+
+	if gameState.IsGameOver() {
+		return ErrGameOver
+	}
+
+	gameState.resignedPlayer = player
+	gameState.resignedTurn = gameState.turn
+	if player == Player1 {
+		gameState.turn = Player2Won
+	} else {
+		gameState.turn = Player1Won
+	}
+
+	gameState.closeMoveListeners()
+	gameState.publish(Event{Type: GameOverEvent, Player: player, Turn: gameState.turn, Resigned: true})
+
+	return nil
+}
+
+func (gameState *GameState) withdrawResignation() {
+	if gameState.resignedPlayer != 0 {
+		gameState.turn = gameState.resignedTurn
+		gameState.resignedPlayer = 0
+	}
+}
+
+// Reset takes back every move, returning the game to its starting position.
+func (gameState *GameState) Reset() {
+	gameState.withdrawResignation()
+	for len(gameState.history) > 0 {
+		gameState.unplayMove()
+	}
+	gameState.redoMoves = gameState.redoMoves[:0]
+
+	gameState.publish(Event{Type: ResetEvent, Turn: gameState.turn})
+}
@@ -0,0 +1,47 @@
+package connect4
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResignRejectsUnknownPlayer(t *testing.T) {
+	gameState := NewGame()
+	for _, player := range []PlayerID{0, Player3, 9} {
+		if err := gameState.Resign(player); !errors.Is(err, ErrUnknownPlayer) {
+			t.Errorf("player %d resigned: %v", player, err)
+		}
+	}
+
+	if gameState.IsGameOver() {
+		t.Fatal("game ended")
+	}
+}
+
+func TestResignClosesMoveListeners(t *testing.T) {
+	gameState := NewGame()
+	moves := make(chan Move, 1)
+	gameState.RegisterMoveListener(moves)
+
+	if err := gameState.MakeMove(DropMove(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gameState.Resign(Player2); err != nil {
+		t.Fatal(err)
+	}
+
+	if move := <-moves; move != DropMove(3) {
+		t.Fatalf("got move %s", move)
+	}
+	select {
+	case _, ok := <-moves:
+		if ok {
+			t.Fatal("move sent after resignation")
+		}
+	default:
+		t.Fatal("listener still open after resignation")
+	}
+	if gameState.GetTurn() != Player1Won {
+		t.Fatalf("turn %d after player 2 resigned", gameState.GetTurn())
+	}
+}
@@ -0,0 +1,285 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+)
+
+// In a fog-of-war game each player sees only their own pieces. They learn
+// where opponent pieces are when a drop lands higher than the pieces they
+// know of, since whatever it landed on must be the opponent's, or when a drop
+// finds the column already full. A Referee holds the true GameState and gives
+// each player an Observation of it.
+
+// Observation is what one player knows of a fog-of-war game.
+type Observation struct {
+	player PlayerID
+	rules  Rules
+	board  *Board
+	// knownHeights is how far up each column the player has seen
+	knownHeights   []int
+	opponentPieces int
+	revealedPieces int
+	turn           Turn
+}
+
+func newObservation(player PlayerID, rules Rules) *Observation {
+	return &Observation{
+		player:       player,
+		rules:        rules,
+		board:        NewBoard(rules.Width, rules.Height),
+		knownHeights: make([]int, rules.Width),
+		turn:         Player1Turn,
+	}
+}
+
+func (observation *Observation) Player() PlayerID {
+	return observation.player
+}
+
+func (observation *Observation) GetRules() Rules {
+	return observation.rules
+}
+
+func (observation *Observation) GetTurn() Turn {
+	return observation.turn
+}
+
+func (observation *Observation) IsGameOver() bool {
+	return observation.turn != Player1Turn && observation.turn != Player2Turn
+}
+
+func (observation *Observation) isTurn() bool {
+	return (observation.turn == Player1Turn && observation.player == Player1) || (observation.turn == Player2Turn && observation.player == Player2)
+}
+
+// GetBoard returns the pieces the player knows of. Cells they haven't seen
+// are empty.
+func (observation *Observation) GetBoard() *Board {
+	return observation.board.Clone()
+}
+
+// KnownHeight returns how many cells of column x the player has seen, which
+// is where their next drop there would land if the opponent hasn't played on
+// it since.
+func (observation *Observation) KnownHeight(x int) int {
+	return observation.knownHeights[x]
+}
+
+// HiddenPieces returns how many of the opponent's pieces the player hasn't
+// seen.
+func (observation *Observation) HiddenPieces() int {
+	return observation.opponentPieces - observation.revealedPieces
+}
+
+// GetPossibleMoves returns the columns the player hasn't seen to be full.
+// Some may turn out to be full when tried.
+func (observation *Observation) GetPossibleMoves() []Move {
+	var moves []Move
+	if observation.IsGameOver() {
+		return moves
+	}
+
+	for x, height := range observation.knownHeights {
+		if height < observation.rules.Height {
+			moves = append(moves, DropMove(x))
+		}
+	}
+
+	return moves
+}
+
+func (observation *Observation) opponentPiece() Piece {
+	if observation.player == Player1 {
+		return Player2Piece
+	}
+
+	return Player1Piece
+}
+
+// reveal marks the cells of column x from the known height up to row as the
+// opponent's, returning them.
+func (observation *Observation) reveal(x int, row int) []Cell {
+	var revealed []Cell
+	for ; observation.knownHeights[x] < row; observation.knownHeights[x]++ {
+		cell := Cell{x, observation.rules.Height - 1 - observation.knownHeights[x]}
+		(*observation.board)[cell.Y][x] = observation.opponentPiece()
+		revealed = append(revealed, cell)
+	}
+	observation.revealedPieces += len(revealed)
+
+	return revealed
+}
+
+func (observation *Observation) Clone() *Observation {
+	knownHeights := make([]int, len(observation.knownHeights))
+	copy(knownHeights, observation.knownHeights)
+
+	clone := *observation
+	clone.board = observation.board.Clone()
+	clone.knownHeights = knownHeights
+
+	return &clone
+}
+
+func (observation *Observation) String() string {
+	output := observation.board.String()
+	output += fmt.Sprintf("%d hidden opponent pieces.\n", observation.HiddenPieces())
+	switch observation.turn {
+	case Draw:
+		output += "Game Over - Draw!\n"
+	case Player1Turn:
+		output += "Player 1's turn.\n"
+	case Player2Turn:
+		output += "Player 2's turn.\n"
+	case Player1Won:
+		output += "Game Over - Player 1 Won!\n"
+	case Player2Won:
+		output += "Game Over - Player 2 Won!\n"
+	default:
+		output += "Invalid Turn!\n"
+	}
+	return output
+}
+
+func (observation *Observation) Print() {
+	print(observation.String())
+}
+
+// ObservedMove is what a player learns from a move. For their own moves it
+// has the column, the row the piece landed on counting up from the bottom and
+// the opponent pieces revealed, with Rejected set if the column turned out to
+// be full. For the opponent's moves only Player and Turn are set.
+type ObservedMove struct {
+	Player   PlayerID
+	Move     Move
+	Row      int
+	Revealed []Cell
+	Rejected bool
+	Turn     Turn
+}
+
+type Referee struct {
+	gameState    *GameState
+	observations [2]*Observation
+	listeners    [2][]chan<- ObservedMove
+}
+
+// NewReferee starts a fog-of-war game. Only drops are hidden, so rules with
+// PopOut or without gravity are refused.
+func NewReferee(rules Rules) (*Referee, error) {
+	if rules.PopOut || rules.GravityFree {
+		return nil, fmt.Errorf("%w: %s can't be played with fog of war", ErrInvalidRules, rules)
+	}
+
+	gameState, err := NewGameWithRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	referee := &Referee{gameState: gameState}
+	referee.observations[0] = newObservation(Player1, rules)
+	referee.observations[1] = newObservation(Player2, rules)
+
+	return referee, nil
+}
+
+func (referee *Referee) GetTurn() Turn {
+	return referee.gameState.turn
+}
+
+func (referee *Referee) IsGameOver() bool {
+	return referee.gameState.IsGameOver()
+}
+
+// GameState returns a copy of the true game, for showing once it is over.
+func (referee *Referee) GameState() *GameState {
+	return referee.gameState.Clone()
+}
+
+// Observation returns a copy of what player knows.
+func (referee *Referee) Observation(player PlayerID) (*Observation, error) {
+	if err := checkPlayer(player); err != nil {
+		return nil, err
+	}
+
+	return referee.observations[player-1].Clone(), nil
+}
+
+// RegisterObserver sends listener what player learns from each move. Like a
+// GameState move listener, it is closed once the game is over.
+func (referee *Referee) RegisterObserver(player PlayerID, listener chan<- ObservedMove) error {
+	if err := checkPlayer(player); err != nil {
+		return err
+	}
+
+	referee.listeners[player-1] = append(referee.listeners[player-1], listener)
+
+	return nil
+}
+
+func checkPlayer(player PlayerID) error {
+	if player != Player1 && player != Player2 {
+		return fmt.Errorf("%w: player %d", ErrUnknownPlayer, player)
+	}
+
+	return nil
+}
+
+// MakeMove plays move for player. A drop on a column that turns out to be
+// full reveals the column and returns a *MoveError wrapping ErrColumnFull,
+// leaving player to move again.
+func (referee *Referee) MakeMove(player PlayerID, move Move) (ObservedMove, error) {
+	gameState := referee.gameState
+	if gameState.IsGameOver() {
+		return ObservedMove{}, &MoveError{move, ErrGameOver}
+	}
+
+	if player != gameState.currentPlayer() {
+		return ObservedMove{}, &MoveError{move, ErrNotYourTurn}
+	}
+
+	observation := referee.observations[player-1]
+	err := gameState.checkMove(move)
+	if err != nil && !errors.Is(err, ErrColumnFull) {
+		return ObservedMove{}, err
+	}
+
+	x := move.Column()
+	row := gameState.heights[x]
+	observed := ObservedMove{Player: player, Move: move, Row: row, Revealed: observation.reveal(x, row), Rejected: err != nil}
+
+	if err == nil {
+		gameState.MakeMove(move)
+		(*observation.board)[observation.rules.Height-1-row][x] = Piece(player)
+		observation.knownHeights[x] = row + 1
+		referee.observations[2-player].opponentPieces++
+	}
+
+	for _, observation := range referee.observations {
+		observation.turn = gameState.turn
+	}
+	observed.Turn = gameState.turn
+
+	referee.announce(player, observed)
+	if err == nil {
+		referee.announce(3-player, ObservedMove{Player: player, Turn: gameState.turn})
+	}
+
+	if gameState.IsGameOver() {
+		for i, listeners := range referee.listeners {
+			for _, listener := range listeners {
+				close(listener)
+			}
+			referee.listeners[i] = nil
+		}
+	}
+
+	return observed, err
+}
+
+func (referee *Referee) announce(player PlayerID, observed ObservedMove) {
+	for _, listener := range referee.listeners[player-1] {
+		listener <- observed
+	}
+}
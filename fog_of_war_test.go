@@ -0,0 +1,33 @@
+package connect4
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRefereeRejectsUnknownPlayers(t *testing.T) {
+	referee, err := NewReferee(StandardRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, player := range []PlayerID{0, Player3} {
+		if _, err := referee.Observation(player); !errors.Is(err, ErrUnknownPlayer) {
+			t.Errorf("observation for player %d: %v", player, err)
+		}
+		if err := referee.RegisterObserver(player, make(chan ObservedMove)); !errors.Is(err, ErrUnknownPlayer) {
+			t.Errorf("observer for player %d: %v", player, err)
+		}
+		if _, err := referee.MakeMove(player, DropMove(3)); !errors.Is(err, ErrNotYourTurn) {
+			t.Errorf("move for player %d: %v", player, err)
+		}
+	}
+
+	observation, err := referee.Observation(Player2)
+	if err != nil || observation.Player() != Player2 {
+		t.Fatalf("got %v, %v", observation, err)
+	}
+	if err := referee.RegisterObserver(Player2, make(chan ObservedMove, 2)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package connect4
+
+import "math/rand"
+
+// sampleAttempts bounds the tries at finding a sample where the hidden pieces
+// don't end the game, which the true position can't have done.
+const sampleAttempts = 100
+
+// Sample fills in the opponent's hidden pieces at random on top of the cells
+// the player has seen, giving a GameState the observation could be of with
+// the player to move. It returns nil if none of its tries was consistent.
+func (observation *Observation) Sample(random *rand.Rand) *GameState {
+	rules := observation.rules
+	turn := Player1Turn
+	if observation.player == Player2 {
+		turn = Player2Turn
+	}
+
+	for attempt := 0; attempt < sampleAttempts; attempt++ {
+		board := observation.board.Clone()
+		heights := make([]int, len(observation.knownHeights))
+		copy(heights, observation.knownHeights)
+
+		placed := 0
+		for ; placed < observation.HiddenPieces(); placed++ {
+			var columns []int
+			for x, height := range heights {
+				if height < rules.Height {
+					columns = append(columns, x)
+				}
+			}
+			if len(columns) == 0 {
+				break
+			}
+
+			x := columns[random.Intn(len(columns))]
+			(*board)[rules.Height-1-heights[x]][x] = observation.opponentPiece()
+			heights[x]++
+		}
+		if placed < observation.HiddenPieces() {
+			return nil
+		}
+
+		gameState := newGameFromBoard(rules, board, Piece(observation.player), turn)
+		if gameState.turn == turn {
+			return gameState
+		}
+	}
+
+	return nil
+}
+
+// SamplingPlayer plays fog of war by scoring each move with a heuristic over
+// boards sampled from its observation, and choosing the best on average. The
+// heuristic must be for the player it plays.
+type SamplingPlayer struct {
+	heuristic Heuristic
+	samples   int
+	random    *rand.Rand
+}
+
+func NewSamplingPlayer(heuristic Heuristic, samples int, random *rand.Rand) *SamplingPlayer {
+	return &SamplingPlayer{heuristic, samples, random}
+}
+
+func (player *SamplingPlayer) ChooseMove(observation *Observation) (Move, error) {
+	if observation.IsGameOver() {
+		return 0, ErrGameOver
+	}
+
+	if !observation.isTurn() {
+		return 0, ErrNotYourTurn
+	}
+
+	moves := observation.GetPossibleMoves()
+	totals := make([]float64, len(moves))
+	counts := make([]int, len(moves))
+
+	for sample := 0; sample < player.samples; sample++ {
+		gameState := observation.Sample(player.random)
+		if gameState == nil {
+			continue
+		}
+
+		for i, move := range moves {
+			// A column full in this sample would only be rejected, so it isn't scored
+			if !gameState.IsValidMove(move) {
+				continue
+			}
+
+			clone := gameState.Clone()
+			clone.playMove(move)
+			totals[i] += player.heuristic.Heuristic(clone)
+			counts[i]++
+		}
+	}
+
+	// Moves no sample could play are only chosen if nothing else can be
+	best := 0
+	bestScore := -2.0
+	for i := range moves {
+		if counts[i] == 0 {
+			continue
+		}
+
+		if score := totals[i] / float64(counts[i]); score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+
+	return moves[best], nil
+}
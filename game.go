@@ -0,0 +1,58 @@
+package connect4
+
+// Game is the part of a game that search needs: the moves available, making
+// and taking them back, and a hash of the position to cache results under.
+// GameState, MultiGameState and ScoreFourGameState all play through it, and
+// an Engine searches GameState and ScoreFourGameState through it.
+type Game interface {
+	GetPossibleMoves() []Move
+	IsValidMove(move Move) bool
+	MakeMove(move Move) error
+	UndoMove() error
+	IsGameOver() bool
+	Hash() uint64
+}
+
+var (
+	_ Game = (*GameState)(nil)
+	_ Game = (*MultiGameState)(nil)
+	_ Game = (*ScoreFourGameState)(nil)
+)
+
+// Heuristic scores a GameState for the player it was made for, from -1.0 for
+// a loss to 1.0 for a win. Every heuristic in the package satisfies it but
+// ScoreFourViabilityHeuristic, which scores a ScoreFourGameState instead.
+type Heuristic interface {
+	Heuristic(gameState *GameState) float64
+}
+
+var (
+	_ Heuristic = (*SimpleHeuristic)(nil)
+	_ Heuristic = (*ViabilityHeuristic)(nil)
+	_ Heuristic = (*ViabilityExtendedHeuristic)(nil)
+	_ Heuristic = (*ZugzwangHeuristic)(nil)
+)
+
+// GameHeuristic scores any Game for the player it was made for, from -1.0 for
+// a loss to 1.0 for a win, as Heuristic does a GameState. CanScore reports
+// whether it knows the kind of game at all. Engines search through it.
+type GameHeuristic interface {
+	CanScore(game Game) bool
+	ScoreGame(game Game) float64
+}
+
+var _ GameHeuristic = (*ScoreFourViabilityHeuristic)(nil)
+
+// gameStateHeuristic lets a Heuristic score GameStates for an Engine.
+type gameStateHeuristic struct {
+	heuristic Heuristic
+}
+
+func (heuristic gameStateHeuristic) CanScore(game Game) bool {
+	_, ok := game.(*GameState)
+	return ok
+}
+
+func (heuristic gameStateHeuristic) ScoreGame(game Game) float64 {
+	return heuristic.heuristic.Heuristic(game.(*GameState))
+}
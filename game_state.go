@@ -1,11 +1,10 @@
 package connect4
 
 import (
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
+	"strings"
 )
 
 type Turn int
@@ -23,12 +22,40 @@ type PlayerID int
 const (
 	Player1 PlayerID = 1
 	Player2          = 2
+	Player3          = 3
 )
 
 type GameState struct {
-	board         *Board
-	turn          Turn
+	layout       *layout
+	current      bitboard
+	mask         bitboard
+	neutral      bitboard
+	heights      []int
+	currentPiece Piece
+	hash         uint64
+	mirrorHash   uint64
+	turn         Turn
+	history      []moveRecord
+	redoMoves    []Move
+
+	// positionCounts tracks repetitions under PopOut, keyed by hash
+	positionCounts map[uint64]int
+
+	// fromSetup marks a game started from a setup, whose piece counts needn't
+	// match the turn
+	fromSetup bool
+	// sideGiven marks a game parsed with a turn its piece counts don't give
+	sideGiven bool
+
+	resignedPlayer PlayerID
+	resignedTurn   Turn
+
 	moveListeners []chan<- Move
+	subscriptions []*Subscription
+}
+
+func (gameState *GameState) GetRules() Rules {
+	return gameState.layout.rules
 }
 
 func (gameState *GameState) GetTurn() Turn {
@@ -40,15 +67,53 @@ func (gameState *GameState) RegisterMoveListener(moveListener chan<- Move) {
 }
 
 func (gameState *GameState) IsValidMove(move Move) bool {
-	if gameState.IsGameOver() {
-		return false
+	return gameState.checkMove(move) == nil
+}
+
+// checkMove returns a *MoveError describing why move can't be played, or nil.
+func (gameState *GameState) checkMove(move Move) error {
+	switch gameState.turn {
+	case Player1Turn, Player2Turn:
+	case Draw, Player1Won, Player2Won:
+		return &MoveError{move, ErrGameOver}
+	default:
+		return &MoveError{move, ErrCorruptState}
 	}
 
-	if move < 0 || int(move) >= BoardWidth {
-		return false
+	if !move.isWellFormed() || move.Column() >= gameState.layout.rules.Width {
+		return &MoveError{move, ErrColumnOutOfRange}
 	}
 
-	return gameState.board[0][move] == EmptyPiece
+	if move.IsCell() != gameState.layout.rules.GravityFree {
+		return &MoveError{move, ErrMoveNotAllowed}
+	}
+
+	if move.IsPop() {
+		if !gameState.layout.rules.PopOut {
+			return &MoveError{move, ErrMoveNotAllowed}
+		}
+
+		if !gameState.current.intersects(gameState.layout.cellMask(move.Column(), 0)) {
+			return &MoveError{move, ErrCannotPop}
+		}
+	} else if move.IsCell() {
+		y := move.Cell().Y
+		if y >= gameState.layout.rules.Height {
+			return &MoveError{move, ErrRowOutOfRange}
+		}
+
+		if gameState.mask.intersects(gameState.layout.cellMask(move.Column(), gameState.layout.rules.Height-1-y)) {
+			return &MoveError{move, ErrCellOccupied}
+		}
+	} else if gameState.heights[move.Column()] >= gameState.layout.rules.Height {
+		return &MoveError{move, ErrColumnFull}
+	}
+
+	if (gameState.turn == Player1Turn) != (gameState.currentPiece == Player1Piece) {
+		return &MoveError{move, ErrCorruptState}
+	}
+
+	return nil
 }
 
 func (gameState *GameState) GetPossibleMoves() []Move {
@@ -58,18 +123,31 @@ func (gameState *GameState) GetPossibleMoves() []Move {
 		return moves
 	}
 
-	for column := 0; column < BoardWidth; column++ {
-		move := Move(column)
+	if gameState.layout.rules.GravityFree {
+		return append(moves, gameState.layout.cellsToMoves(gameState.playableCells())...)
+	}
+
+	for column := 0; column < gameState.layout.rules.Width; column++ {
+		move := DropMove(column)
 		if gameState.IsValidMove(move) {
 			moves = append(moves, move)
 		}
 	}
 
+	if gameState.layout.rules.PopOut {
+		for column := 0; column < gameState.layout.rules.Width; column++ {
+			move := PopMove(column)
+			if gameState.IsValidMove(move) {
+				moves = append(moves, move)
+			}
+		}
+	}
+
 	return moves
 }
 
 func (gameState *GameState) String() string {
-	output := gameState.board.String()
+	output := gameState.GetBoard().String()
 	switch gameState.turn {
 	case Draw:
 		output += "Game Over - Draw!\n"
@@ -95,275 +173,280 @@ func (gameState *GameState) IsGameOver() bool {
 	return gameState.turn != Player1Turn && gameState.turn != Player2Turn
 }
 
-func (gameState *GameState) verifyEndGame() {
+// verifyEndGame updates the turn if the game has ended. popper is the player
+// who just popped a piece out, or EmptyPiece, since a pop can complete lines
+// for both players at once.
+func (gameState *GameState) verifyEndGame(popper Piece) {
 	if gameState.turn != Player1Turn && gameState.turn != Player2Turn {
 		return
 	}
 
-	///////////////////////////
-	// Search for 4 in a row //
-	///////////////////////////
-
-	// Search horizontally
-	for y := 0; y < BoardHeight; y++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-		x := 0
-		for ; x < 3; x++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-		}
-
-		for ; x < BoardWidth; x++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			if player1PieceCount == 4 {
-				gameState.turn = Player1Won
-				return
-			} else if player2PieceCount == 4 {
-				gameState.turn = Player2Won
-				return
-			}
-
-			switch gameState.board[y][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
-		}
+	player1Connected := gameState.layout.hasConnect(gameState.getPlayerPieces(Player1Piece))
+	player2Connected := gameState.layout.hasConnect(gameState.getPlayerPieces(Player2Piece))
+
+	// A pop that completes lines for both players loses, whatever the lines mean
+	if player1Connected && player2Connected && popper == Player1Piece {
+		gameState.turn = Player2Won
+	} else if player1Connected && player2Connected && popper == Player2Piece {
+		gameState.turn = Player1Won
+	} else if player1Connected {
+		gameState.turn = gameState.connectResult(Player1Piece)
+	} else if player2Connected {
+		gameState.turn = gameState.connectResult(Player2Piece)
+	} else if gameState.positionCounts[gameState.hash] >= repetitionLimit {
+		gameState.turn = Draw
+	} else if !gameState.hasLegalMove() {
+		gameState.turn = Draw
 	}
+}
 
-	// Search vertically
-	for x := 0; x < BoardWidth; x++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-		y := 0
-		for ; y < 3; y++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-		}
-
-		for ; y < BoardHeight; y++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+// connectResult is the result of piece completing a line.
+func (gameState *GameState) connectResult(piece Piece) Turn {
+	if (piece == Player1Piece) != gameState.layout.rules.Misere {
+		return Player1Won
+	}
 
-			if player1PieceCount == 4 {
-				gameState.turn = Player1Won
-				return
-			} else if player2PieceCount == 4 {
-				gameState.turn = Player2Won
-				return
-			}
+	return Player2Won
+}
 
-			switch gameState.board[y-3][x] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
-		}
+func (gameState *GameState) hasLegalMove() bool {
+	if gameState.mask != gameState.layout.boardMask {
+		return true
 	}
 
-	// Search diagonally up
-	for xIndex := 4 - BoardHeight; xIndex < BoardWidth-2; xIndex++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-
-		var (
-			x int
-			y int
-		)
-		if xIndex < 0 {
-			x = 0
-			y = -xIndex
-		} else {
-			x = xIndex
-			y = 0
-		}
+	return gameState.layout.rules.PopOut && gameState.current.intersects(gameState.layout.bottomMask)
+}
 
-		for i := 0; i < 3; i++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+func (gameState *GameState) MakeMove(move Move) error {
+	if err := gameState.checkMove(move); err != nil {
+		return err
+	}
 
-			x++
-			y++
-		}
+	player := gameState.currentPlayer()
+	gameState.playMove(move)
+	gameState.redoMoves = gameState.redoMoves[:0]
+	gameState.announceMove(move, player)
 
-		for x < BoardWidth && y < BoardHeight {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+	return nil
+}
 
-			if player1PieceCount == 4 {
-				gameState.turn = Player1Won
-				return
-			} else if player2PieceCount == 4 {
-				gameState.turn = Player2Won
-				return
-			}
+func (gameState *GameState) playMove(move Move) {
+	gameState.history = append(gameState.history, moveRecord{move, gameState.turn})
 
-			switch gameState.board[y-3][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
+	x := move.Column()
+	popper := EmptyPiece
+	if move.IsPop() {
+		popper = gameState.currentPiece
+		gameState.popPiece(x)
+	} else {
+		row := gameState.heights[x]
+		if move.IsCell() {
+			row = gameState.layout.rules.Height - 1 - move.Cell().Y
+		}
 
-			x++
-			y++
+		piece := gameState.layout.cellMask(x, row)
+		gameState.current = gameState.current.or(piece)
+		gameState.mask = gameState.mask.or(piece)
+		gameState.hash ^= gameState.layout.zobristPiece(gameState.currentPiece, x, row)
+		gameState.mirrorHash ^= gameState.layout.zobristPiece(gameState.currentPiece, gameState.layout.rules.Width-1-x, row)
+		if row >= gameState.heights[x] {
+			gameState.heights[x] = row + 1
 		}
 	}
+	gameState.hash ^= gameState.layout.zobristSide
+	gameState.mirrorHash ^= gameState.layout.zobristSide
 
-	// Search diagonally down
-	for xIndex := 4 - BoardHeight; xIndex < BoardWidth-3; xIndex++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-
-		var (
-			x int
-			y int
-		)
-		if xIndex < 0 {
-			x = 0
-			y = BoardHeight - 1 + xIndex
-		} else {
-			x = xIndex
-			y = BoardHeight - 1
-		}
+	gameState.current = gameState.current.xor(gameState.mask).andNot(gameState.neutral)
+	if gameState.turn == Player1Turn {
+		gameState.turn = Player2Turn
+		gameState.currentPiece = Player2Piece
+	} else if gameState.turn == Player2Turn {
+		gameState.turn = Player1Turn
+		gameState.currentPiece = Player1Piece
+	}
 
-		for i := 0; i < 3; i++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+	if gameState.positionCounts != nil {
+		gameState.positionCounts[gameState.hash]++
+	}
 
-			x++
-			y--
-		}
+	gameState.verifyEndGame(popper)
+}
 
-		for x < BoardWidth && y >= 0 {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+func NewGame() *GameState {
+	gameState, _ := NewGameWithRules(StandardRules)
+	return gameState
+}
 
-			if player1PieceCount == 4 {
-				gameState.turn = Player1Won
-				return
-			} else if player2PieceCount == 4 {
-				gameState.turn = Player2Won
-				return
-			}
+func NewGameWithRules(rules Rules) (*GameState, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
 
-			switch gameState.board[y+3][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
+	if rules.PlayerCount() > 2 {
+		return nil, fmt.Errorf("%w: %d players, use NewMultiGame", ErrInvalidRules, rules.PlayerCount())
+	}
 
-			x++
-			y--
-		}
+	gameState := &GameState{
+		layout:       getLayout(rules),
+		heights:      make([]int, rules.Width),
+		currentPiece: Player1Piece,
+		turn:         Player1Turn,
 	}
+	gameState.initPositionCounts()
 
-	///////////////////////////////////////////
-	// If nobody has won, then check for a draw
-	///////////////////////////////////////////
+	return gameState, nil
+}
 
-	haveValidMove := false
-	for x := 0; x < BoardWidth; x++ {
-		move := Move(x)
-		if gameState.IsValidMove(move) {
-			haveValidMove = true
-			break
-		}
+// NewGameFromSetup starts a handicap or puzzle game from board, which may
+// have neutral pieces and any number of each player's pieces, with toMove to
+// play first. The board is checked with Board.ValidateSetup.
+func NewGameFromSetup(rules Rules, board *Board, toMove PlayerID) (*GameState, error) {
+	if err := board.ValidateSetup(rules); err != nil {
+		return nil, err
 	}
 
-	if !haveValidMove {
-		gameState.turn = Draw
+	if rules.PlayerCount() > 2 {
+		return nil, fmt.Errorf("%w: %d players, use NewMultiGame", ErrInvalidRules, rules.PlayerCount())
 	}
+
+	var gameState *GameState
+	switch toMove {
+	case Player1:
+		gameState = newGameFromBoard(rules, board, Player1Piece, Player1Turn)
+	case Player2:
+		gameState = newGameFromBoard(rules, board, Player2Piece, Player2Turn)
+	default:
+		return nil, fmt.Errorf("%w: player %d to move", ErrInvalidGameDescription, toMove)
+	}
+	gameState.fromSetup = true
+
+	return gameState, nil
 }
 
-func (gameState *GameState) MakeMove(move Move) error {
-	if !gameState.IsValidMove(move) {
-		return errors.New("Invalid Move!")
+func newGameFromBoard(rules Rules, board *Board, currentPiece Piece, turn Turn) *GameState {
+	gameState := &GameState{
+		layout:       getLayout(rules),
+		heights:      make([]int, rules.Width),
+		currentPiece: currentPiece,
+		turn:         turn,
 	}
 
-	for y := BoardHeight - 1; y >= 0; y-- {
-		if gameState.board[y][move] == EmptyPiece {
-			switch gameState.turn {
-			case Player1Turn:
-				gameState.board[y][move] = Player1Piece
-			case Player2Turn:
-				gameState.board[y][move] = Player2Piece
-			default:
-				log.Fatal("Invalid Move!")
+	for x := 0; x < rules.Width; x++ {
+		for row := 0; row < rules.Height; row++ {
+			piece := (*board)[rules.Height-1-row][x]
+			if piece == EmptyPiece {
+				continue
+			}
+
+			gameState.mask = gameState.mask.or(gameState.layout.cellMask(x, row))
+			if piece == NeutralPiece {
+				gameState.neutral = gameState.neutral.or(gameState.layout.cellMask(x, row))
+			} else if piece == currentPiece {
+				gameState.current = gameState.current.or(gameState.layout.cellMask(x, row))
 			}
-			break
+			gameState.heights[x] = row + 1
 		}
 	}
 
-	if gameState.turn == Player1Turn {
-		gameState.turn = Player2Turn
-	} else if gameState.turn == Player2Turn {
-		gameState.turn = Player1Turn
+	gameState.hash = gameState.computeHash()
+	gameState.mirrorHash = gameState.Mirror().computeHash()
+	gameState.initPositionCounts()
+	gameState.verifyEndGame(EmptyPiece)
+
+	return gameState
+}
+
+func (gameState *GameState) Clone() *GameState {
+	heights := make([]int, len(gameState.heights))
+	copy(heights, gameState.heights)
+	history := make([]moveRecord, len(gameState.history))
+	copy(history, gameState.history)
+	redoMoves := make([]Move, len(gameState.redoMoves))
+	copy(redoMoves, gameState.redoMoves)
+
+	return &GameState{
+		layout:       gameState.layout,
+		current:      gameState.current,
+		mask:         gameState.mask,
+		neutral:      gameState.neutral,
+		heights:      heights,
+		currentPiece: gameState.currentPiece,
+		hash:         gameState.hash,
+		mirrorHash:   gameState.mirrorHash,
+		turn:         gameState.turn,
+		history:      history,
+		redoMoves:    redoMoves,
+
+		positionCounts: gameState.clonePositionCounts(),
+		fromSetup:      gameState.fromSetup,
+		sideGiven:      gameState.sideGiven,
+
+		resignedPlayer: gameState.resignedPlayer,
+		resignedTurn:   gameState.resignedTurn,
 	}
+}
 
-	gameState.verifyEndGame()
+func (gameState *GameState) currentPlayer() PlayerID {
+	return pieceToPlayer(gameState.currentPiece)
+}
 
-	for _, moveListener := range gameState.moveListeners {
-		moveListener <- move
+// playableCells returns the next free cell of each column. Unlike
+// layout.playableCells it copes with the gaps a lenient setup may have.
+// Without gravity every empty cell is playable.
+func (gameState *GameState) playableCells() bitboard {
+	if gameState.layout.rules.GravityFree {
+		return gameState.layout.boardMask.andNot(gameState.mask)
 	}
 
-	if gameState.IsGameOver() {
-		for _, moveListener := range gameState.moveListeners {
-			close(moveListener)
+	var cells bitboard
+	for x, height := range gameState.heights {
+		if height < gameState.layout.rules.Height {
+			cells = cells.or(gameState.layout.cellMask(x, height))
 		}
 	}
 
-	return nil
+	return cells
 }
 
-func NewGame() *GameState {
-	return &GameState{&Board{}, Player1Turn, nil}
+func (gameState *GameState) getPlayerPieces(piece Piece) bitboard {
+	if piece == gameState.currentPiece {
+		return gameState.current
+	}
+
+	return gameState.current.xor(gameState.mask).andNot(gameState.neutral)
 }
 
-func (gameState *GameState) Clone() *GameState {
-	return &GameState{gameState.board.Clone(), gameState.turn, nil}
+// GetBoard builds a Board view of the current position.
+func (gameState *GameState) GetBoard() *Board {
+	rules := gameState.layout.rules
+	board := NewBoard(rules.Width, rules.Height)
+	player1Pieces := gameState.getPlayerPieces(Player1Piece)
+
+	for y := 0; y < rules.Height; y++ {
+		for x := 0; x < rules.Width; x++ {
+			piece := gameState.layout.cellMask(x, rules.Height-1-y)
+			if !gameState.mask.intersects(piece) {
+				(*board)[y][x] = EmptyPiece
+			} else if gameState.neutral.intersects(piece) {
+				(*board)[y][x] = NeutralPiece
+			} else if player1Pieces.intersects(piece) {
+				(*board)[y][x] = Player1Piece
+			} else {
+				(*board)[y][x] = Player2Piece
+			}
+		}
+	}
+
+	return board
 }
 
-/* GameState File Format:7x6 array of (RY )
- * Turn is determined by count of R vs Y
+/* GameState File Format: Width x Height grid of (RY ) cells between '|'
+ * Rows with column numbers and lines without a '|' are ignored, as are the
+ * '*' marks StringMarked puts around cells.
+ * Turn is taken from a "Player N's turn." line if there is one, as PopOut
+ * and setups need, otherwise it is determined by count of R vs Y
+ * '#' is a neutral blocker, and G, B, P and O are the pieces of players 3 on
+ * in MultiGameState games.
  */
 
 func (gameState *GameState) Save(filename string) error {
@@ -379,66 +462,173 @@ func (gameState *GameState) Save(filename string) error {
 	return nil
 }
 
+func parseBoard(gameDescription string) (*Board, error) {
+	board := Board{}
+
+	for _, line := range strings.Split(gameDescription, "\n") {
+		start := strings.Index(line, "|")
+		end := strings.LastIndex(line, "|")
+		if start < 0 || end == start {
+			continue
+		}
+
+		cells := strings.Split(line[start+1:end], "|")
+		row := make([]Piece, len(cells))
+		isHeader := true
+		for x, cell := range cells {
+			cell = strings.Trim(cell, " *")
+			if cell == "" {
+				row[x] = EmptyPiece
+				isHeader = false
+			} else if cell == neutralSymbol {
+				row[x] = NeutralPiece
+				isHeader = false
+			} else if piece := strings.Index(pieceSymbols, cell); len(cell) == 1 && piece > 0 {
+				row[x] = Piece(piece)
+				isHeader = false
+			} else if strings.Trim(cell, "0123456789") != "" {
+				return nil, fmt.Errorf("%w: unknown piece '%s'", ErrInvalidGameDescription, cell)
+			}
+		}
+
+		if isHeader {
+			continue
+		}
+
+		if len(board) > 0 && len(row) != board.Width() {
+			return nil, fmt.Errorf("%w: row %d has %d cells, expected %d", ErrInvalidGameDescription, len(board), len(row), board.Width())
+		}
+
+		board = append(board, row)
+	}
+
+	if len(board) == 0 {
+		return nil, fmt.Errorf("%w: no board found", ErrInvalidGameDescription)
+	}
+
+	return &board, nil
+}
+
+type ParseOptions struct {
+	// Rules to parse with. If the size is left zero it is taken from the
+	// description, and a zero connect length then means 4.
+	Rules Rules
+	// Lenient accepts positions that can't arise in play, such as puzzle
+	// setups with floating pieces. The turn comes from the "Player N's turn."
+	// line if there is one, otherwise from the piece counts.
+	Lenient bool
+	// Setup reads the starting position of a handicap or puzzle game, as
+	// NewGameFromSetup takes. The piece counts needn't match, so the turn
+	// comes from the "Player N's turn." line, or else player 1 moves.
+	Setup bool
+}
+
+// ParseGame reads a board of any size, assuming connect four.
 func ParseGame(gameDescription string) (*GameState, error) {
-	gameState := &GameState{&Board{}, Player1Turn, nil}
+	return ParseGameWithOptions(gameDescription, ParseOptions{})
+}
+
+func ParseGameWithRules(gameDescription string, rules Rules) (*GameState, error) {
+	return ParseGameWithOptions(gameDescription, ParseOptions{Rules: rules})
+}
+
+func ParseGameWithOptions(gameDescription string, options ParseOptions) (*GameState, error) {
+	board, err := parseBoard(gameDescription)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := options.Rules
+	if rules.Width == 0 && rules.Height == 0 {
+		rules.Width = board.Width()
+		rules.Height = board.Height()
+		if rules.ConnectLength == 0 {
+			rules.ConnectLength = 4
+		}
+	} else if board.Width() != rules.Width || board.Height() != rules.Height {
+		return nil, fmt.Errorf("%w: %dx%d board, expected %dx%d", ErrInvalidGameDescription, board.Width(), board.Height(), rules.Width, rules.Height)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !options.Lenient {
+		validate := board.Validate
+		if options.Setup {
+			validate = board.ValidateSetup
+		}
+		if err := validate(rules); err != nil {
+			return nil, err
+		}
+	}
 
 	player1PieceCount := 0
 	player2PieceCount := 0
-
-	y := 0
-	x := 0
-	expectingPiece := false
-	for _, c := range gameDescription {
-		if !expectingPiece {
-			if c == '|' {
-				expectingPiece = true
-			}
-		} else {
-			switch c {
-			case 'R':
-				gameState.board[y][x] = Player1Piece
+	for _, row := range *board {
+		for _, piece := range row {
+			switch piece {
+			case EmptyPiece, NeutralPiece:
+			case Player1Piece:
 				player1PieceCount++
-				expectingPiece = false
-			case 'Y':
-				gameState.board[y][x] = Player2Piece
+			case Player2Piece:
 				player2PieceCount++
-				expectingPiece = false
-			case '|':
-				gameState.board[y][x] = EmptyPiece
-			case '\n':
-				expectingPiece = false
-				continue
 			default:
-				continue
-			}
-
-			if x < BoardWidth-1 {
-				x++
-			} else {
-				y++
-				x = 0
-			}
-
-			if y >= BoardHeight {
-				break
+				return nil, fmt.Errorf("%w: piece '%s' in a two player game", ErrInvalidGameDescription, piece.symbol())
 			}
 		}
 	}
 
+	countedPiece := EmptyPiece
 	if player1PieceCount == player2PieceCount {
-		gameState.turn = Player1Turn
+		countedPiece = Player1Piece
 	} else if player1PieceCount == player2PieceCount+1 {
-		gameState.turn = Player2Turn
-	} else {
-		gameState.Print()
-		return nil, errors.New(fmt.Sprintf("invalid gameState description: (%d red pieces, %d yellow pieces)", player1PieceCount, player2PieceCount))
+		countedPiece = Player2Piece
 	}
 
-	gameState.verifyEndGame()
+	currentPiece := parseTurn(gameDescription)
+	if currentPiece > Player2Piece {
+		return nil, fmt.Errorf("%w: player %d's turn in a two player game", ErrInvalidGameDescription, currentPiece)
+	}
+	if currentPiece == EmptyPiece {
+		// Pops upset the counts, and a finished PopOut game has no turn line,
+		// so fall back to Player 1 rather than refuse the description
+		if (countedPiece == EmptyPiece && rules.PopOut) || options.Setup {
+			countedPiece = Player1Piece
+		}
+		if countedPiece == EmptyPiece {
+			return nil, fmt.Errorf("%w: no turn line, and %d red pieces, %d yellow pieces don't give the turn", ErrInvalidGameDescription, player1PieceCount, player2PieceCount)
+		}
+		currentPiece = countedPiece
+	} else if currentPiece != countedPiece && !options.Lenient && !options.Setup && !rules.PopOut {
+		return nil, &ValidationError{[]ValidationProblem{{Err: ErrWrongTurn}}}
+	}
+
+	var gameState *GameState
+	if currentPiece == Player1Piece {
+		gameState = newGameFromBoard(rules, board, Player1Piece, Player1Turn)
+	} else {
+		gameState = newGameFromBoard(rules, board, Player2Piece, Player2Turn)
+	}
+	gameState.fromSetup = options.Setup
+	gameState.sideGiven = currentPiece != countedPiece
 
 	return gameState, nil
 }
 
+// parseTurn looks for the "Player N's turn." line that String writes,
+// returning the piece of the player to move or EmptyPiece if there isn't one.
+func parseTurn(gameDescription string) Piece {
+	for _, line := range strings.Split(gameDescription, "\n") {
+		var player int
+		if _, err := fmt.Sscanf(strings.TrimSpace(line), "Player %d's turn.", &player); err == nil && player > 0 {
+			return Piece(player)
+		}
+	}
+
+	return EmptyPiece
+}
+
 func LoadGame(filename string) (*GameState, error) {
 	gameDescriptionBytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -447,3 +637,16 @@ func LoadGame(filename string) (*GameState, error) {
 
 	return ParseGame(string(gameDescriptionBytes))
 }
+
+func LoadGameWithRules(filename string, rules Rules) (*GameState, error) {
+	return LoadGameWithOptions(filename, ParseOptions{Rules: rules})
+}
+
+func LoadGameWithOptions(filename string, options ParseOptions) (*GameState, error) {
+	gameDescriptionBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseGameWithOptions(string(gameDescriptionBytes), options)
+}
@@ -0,0 +1,18 @@
+package connect4
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseGameReportsPieceCounts(t *testing.T) {
+	board := *NewBoard(7, 6)
+	board[5][0] = Player2Piece
+	board[5][1] = Player2Piece
+
+	_, err := ParseGameWithOptions(board.String(), ParseOptions{Lenient: true})
+	if !errors.Is(err, ErrInvalidGameDescription) || !strings.Contains(err.Error(), "0 red pieces, 2 yellow pieces") {
+		t.Fatalf("got %v", err)
+	}
+}
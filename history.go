@@ -0,0 +1,118 @@
+package connect4
+
+// moveRecord keeps what is needed to take a move back exactly.
+type moveRecord struct {
+	move Move
+	turn Turn
+}
+
+// History returns the moves made in this game, oldest first.
+func (gameState *GameState) History() []Move {
+	moves := make([]Move, len(gameState.history))
+	for i, record := range gameState.history {
+		moves[i] = record.move
+	}
+
+	return moves
+}
+
+func (gameState *GameState) CanUndo() bool {
+	return len(gameState.history) > 0 || gameState.resignedPlayer != 0
+}
+
+func (gameState *GameState) CanRedo() bool {
+	return len(gameState.redoMoves) > 0
+}
+
+// UndoMove takes back the last move, restoring the board, turn and game over
+// status to what they were before it. The move can be replayed with RedoMove.
+func (gameState *GameState) UndoMove() error {
+	if gameState.resignedPlayer != 0 {
+		player := gameState.resignedPlayer
+		gameState.withdrawResignation()
+		gameState.publish(Event{Type: MoveUndoneEvent, Player: player, Turn: gameState.turn, Resigned: true})
+		return nil
+	}
+
+	if !gameState.CanUndo() {
+		return ErrNothingToUndo
+	}
+
+	move := gameState.unplayMove()
+	gameState.redoMoves = append(gameState.redoMoves, move)
+	gameState.publish(Event{Type: MoveUndoneEvent, Move: move, Player: gameState.currentPlayer(), Turn: gameState.turn})
+
+	return nil
+}
+
+// RedoMove replays the most recently undone move.
+func (gameState *GameState) RedoMove() error {
+	if !gameState.CanRedo() {
+		return ErrNothingToRedo
+	}
+
+	move := gameState.redoMoves[len(gameState.redoMoves)-1]
+	if err := gameState.checkMove(move); err != nil {
+		return err
+	}
+
+	player := gameState.currentPlayer()
+	gameState.redoMoves = gameState.redoMoves[:len(gameState.redoMoves)-1]
+	gameState.playMove(move)
+	gameState.announceMove(move, player)
+
+	return nil
+}
+
+// columnHeight returns one more than the row of the highest piece in column x.
+func (gameState *GameState) columnHeight(x int) int {
+	for row := gameState.layout.rules.Height; row > 0; row-- {
+		if gameState.mask.intersects(gameState.layout.cellMask(x, row-1)) {
+			return row
+		}
+	}
+
+	return 0
+}
+
+func (gameState *GameState) unplayMove() Move {
+	record := gameState.history[len(gameState.history)-1]
+	gameState.history = gameState.history[:len(gameState.history)-1]
+
+	if gameState.positionCounts != nil {
+		gameState.positionCounts[gameState.hash]--
+		if gameState.positionCounts[gameState.hash] == 0 {
+			delete(gameState.positionCounts, gameState.hash)
+		}
+	}
+
+	// Switch back to the mover's pieces before changing theirs on the board
+	gameState.current = gameState.current.xor(gameState.mask).andNot(gameState.neutral)
+	gameState.turn = record.turn
+	if record.turn == Player1Turn {
+		gameState.currentPiece = Player1Piece
+	} else {
+		gameState.currentPiece = Player2Piece
+	}
+
+	x := record.move.Column()
+	if record.move.IsPop() {
+		gameState.unpopPiece(x)
+	} else {
+		row := gameState.heights[x] - 1
+		if record.move.IsCell() {
+			row = gameState.layout.rules.Height - 1 - record.move.Cell().Y
+		}
+
+		piece := gameState.layout.cellMask(x, row)
+		gameState.current = gameState.current.andNot(piece)
+		gameState.mask = gameState.mask.andNot(piece)
+		gameState.hash ^= gameState.layout.zobristPiece(gameState.currentPiece, x, row)
+		gameState.mirrorHash ^= gameState.layout.zobristPiece(gameState.currentPiece, gameState.layout.rules.Width-1-x, row)
+		gameState.heights[x] = gameState.columnHeight(x)
+	}
+	gameState.hash ^= gameState.layout.zobristSide
+	gameState.mirrorHash ^= gameState.layout.zobristSide
+
+	return record.move
+}
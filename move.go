@@ -2,24 +2,69 @@ package connect4
 
 import (
 	"fmt"
-	"strconv"
 )
 
+/* Move encoding: the low byte is the column and bit 8 marks a PopOut pop, so
+ * Move(column) is still a plain drop in that column. Cell moves for
+ * gravity-free rules keep the cell's Y plus one in the bits from 9 up.
+ */
 type Move int
 
+const (
+	moveColumnBits Move = 0xff
+	movePopFlag    Move = 1 << 8
+	moveCellShift       = 9
+)
+
+func DropMove(column int) Move {
+	return Move(column)
+}
+
+// PopMove removes the player's own piece from the bottom of column, under
+// PopOut rules.
+func PopMove(column int) Move {
+	return Move(column) | movePopFlag
+}
+
+// CellMove places a piece straight into cell, under GravityFree rules.
+func CellMove(cell Cell) Move {
+	return Move(cell.X) | Move(cell.Y+1)<<moveCellShift
+}
+
 func ParseMove(moveString string) (Move, error) {
-	move, err := strconv.Atoi(moveString)
-	if err != nil {
-		return 0, fmt.Errorf("unable to parse move: %s", err)
-	}
+	return StandardRules.ParseMove(moveString)
+}
 
-	if move < 0 || int(move) >= BoardWidth {
-		return 0, fmt.Errorf("invalid move: %d", move)
-	}
+func (move Move) Column() int {
+	return int(move & moveColumnBits)
+}
+
+func (move Move) IsPop() bool {
+	return move >= 0 && move&movePopFlag != 0
+}
 
-	return Move(move), nil
+func (move Move) IsCell() bool {
+	return move >= 0 && move>>moveCellShift != 0
+}
+
+// Cell returns the cell a cell move places its piece in.
+func (move Move) Cell() Cell {
+	return Cell{move.Column(), int(move>>moveCellShift) - 1}
+}
+
+func (move Move) isWellFormed() bool {
+	return move >= 0 && !(move.IsPop() && move.IsCell())
 }
 
 func (move Move) String() string {
+	if move.IsPop() {
+		return fmt.Sprintf("p%d", move.Column())
+	}
+
+	if move.IsCell() {
+		cell := move.Cell()
+		return fmt.Sprintf("%d,%d", cell.X, cell.Y)
+	}
+
 	return fmt.Sprintf("%d", move)
 }
@@ -0,0 +1,61 @@
+package connect4
+
+// MultiHeuristic scores a position from every player's point of view at once,
+// indexed by PlayerID - 1, as max-n search needs. Paranoid search plays one
+// player's score against the coalition of the rest.
+type MultiHeuristic interface {
+	Scores(gameState *MultiGameState) []float64
+}
+
+// MultiViabilityHeuristic is ViabilityHeuristic for any number of players: a
+// window counts for the only player with pieces in it.
+type MultiViabilityHeuristic struct{}
+
+func NewMultiViabilityHeuristic() *MultiViabilityHeuristic {
+	return &MultiViabilityHeuristic{}
+}
+
+func (heuristic *MultiViabilityHeuristic) Scores(gameState *MultiGameState) []float64 {
+	scores := make([]float64, gameState.Players())
+
+	if gameState.IsGameOver() {
+		if gameState.winner != 0 {
+			for player := range scores {
+				scores[player] = -1.0
+			}
+			scores[gameState.winner-1] = 1.0
+		}
+		return scores
+	}
+
+	viabilities := make([]int, len(scores))
+	totalViability := 0
+	connectLength := gameState.layout.rules.ConnectLength
+	for _, window := range gameState.layout.windows {
+		owner := -1
+		pieceCount := 0
+		for player, pieces := range gameState.pieces {
+			if count := pieces.and(window).count(); count > 0 {
+				if owner >= 0 {
+					owner = -1
+					break
+				}
+				owner = player
+				pieceCount = count
+			}
+		}
+
+		if owner >= 0 {
+			viabilities[owner] += viabilityScore(connectLength - pieceCount)
+			totalViability += viabilityScore(connectLength - pieceCount)
+		}
+	}
+
+	// Score each player against the average of the others
+	for player, viability := range viabilities {
+		othersViability := float64(totalViability-viability) / float64(len(scores)-1)
+		scores[player] = (float64(viability) - othersViability) / float64(100+totalViability)
+	}
+
+	return scores
+}
@@ -0,0 +1,399 @@
+package connect4
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Games for more than two players use MultiGameState. Players take turns in
+// PlayerID order, each with a colour of their own, and the first to complete
+// a line wins. It leaves out the two player extras of GameState such as
+// PopOut, events and threat analysis.
+
+// maxPlayers is how many players have a symbol to render their pieces with.
+const maxPlayers = len(pieceSymbols) - 1
+
+type MultiGameState struct {
+	layout  *layout
+	pieces  []bitboard // Indexed by PlayerID - 1
+	mask    bitboard
+	heights []int
+	current PlayerID
+	winner  PlayerID
+	over    bool
+	hash    uint64
+	history []Move
+}
+
+func NewMultiGame(rules Rules) (*MultiGameState, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	if rules.PopOut || rules.Misere || rules.GravityFree {
+		return nil, fmt.Errorf("%w: %s, use NewGameWithRules", ErrInvalidRules, rules)
+	}
+
+	layout := getLayout(rules)
+	gameState := &MultiGameState{
+		layout:  layout,
+		pieces:  make([]bitboard, rules.PlayerCount()),
+		heights: make([]int, rules.Width),
+		current: Player1,
+	}
+
+	return gameState, nil
+}
+
+func (gameState *MultiGameState) GetRules() Rules {
+	return gameState.layout.rules
+}
+
+func (gameState *MultiGameState) Players() int {
+	return len(gameState.pieces)
+}
+
+// CurrentPlayer returns the player to move, or the player who would have
+// been once the game is over.
+func (gameState *MultiGameState) CurrentPlayer() PlayerID {
+	return gameState.current
+}
+
+// Winner returns the player who completed a line, or 0 if nobody has.
+func (gameState *MultiGameState) Winner() PlayerID {
+	return gameState.winner
+}
+
+func (gameState *MultiGameState) IsGameOver() bool {
+	return gameState.over
+}
+
+func (gameState *MultiGameState) IsDraw() bool {
+	return gameState.over && gameState.winner == 0
+}
+
+func (gameState *MultiGameState) IsValidMove(move Move) bool {
+	return gameState.checkMove(move) == nil
+}
+
+func (gameState *MultiGameState) checkMove(move Move) error {
+	if gameState.over {
+		return &MoveError{move, ErrGameOver}
+	}
+
+	if !move.isWellFormed() || move.Column() >= gameState.layout.rules.Width {
+		return &MoveError{move, ErrColumnOutOfRange}
+	}
+
+	if move.IsPop() {
+		return &MoveError{move, ErrMoveNotAllowed}
+	}
+
+	if gameState.heights[move.Column()] >= gameState.layout.rules.Height {
+		return &MoveError{move, ErrColumnFull}
+	}
+
+	return nil
+}
+
+func (gameState *MultiGameState) GetPossibleMoves() []Move {
+	var moves []Move
+	if gameState.over {
+		return moves
+	}
+
+	for x, height := range gameState.heights {
+		if height < gameState.layout.rules.Height {
+			moves = append(moves, DropMove(x))
+		}
+	}
+
+	return moves
+}
+
+func (gameState *MultiGameState) nextPlayer(player PlayerID) PlayerID {
+	if int(player) == len(gameState.pieces) {
+		return Player1
+	}
+
+	return player + 1
+}
+
+func (gameState *MultiGameState) previousPlayer(player PlayerID) PlayerID {
+	if player == Player1 {
+		return PlayerID(len(gameState.pieces))
+	}
+
+	return player - 1
+}
+
+func (gameState *MultiGameState) MakeMove(move Move) error {
+	if err := gameState.checkMove(move); err != nil {
+		return err
+	}
+
+	layout := gameState.layout
+	x := move.Column()
+	cell := layout.cellMask(x, gameState.heights[x])
+	pieces := &gameState.pieces[gameState.current-1]
+
+	*pieces = pieces.or(cell)
+	gameState.mask = gameState.mask.or(cell)
+	gameState.hash ^= layout.zobristPiece(Piece(gameState.current), x, gameState.heights[x])
+	gameState.heights[x]++
+	gameState.history = append(gameState.history, move)
+
+	if layout.hasConnect(*pieces) {
+		gameState.winner = gameState.current
+		gameState.over = true
+	} else if gameState.mask == layout.boardMask {
+		gameState.over = true
+	}
+
+	gameState.hash ^= layout.zobristTurns[gameState.current-1]
+	gameState.current = gameState.nextPlayer(gameState.current)
+	gameState.hash ^= layout.zobristTurns[gameState.current-1]
+
+	return nil
+}
+
+func (gameState *MultiGameState) UndoMove() error {
+	if len(gameState.history) == 0 {
+		return ErrNothingToUndo
+	}
+
+	layout := gameState.layout
+	move := gameState.history[len(gameState.history)-1]
+	gameState.history = gameState.history[:len(gameState.history)-1]
+
+	gameState.hash ^= layout.zobristTurns[gameState.current-1]
+	gameState.current = gameState.previousPlayer(gameState.current)
+	gameState.hash ^= layout.zobristTurns[gameState.current-1]
+
+	x := move.Column()
+	gameState.heights[x]--
+	cell := layout.cellMask(x, gameState.heights[x])
+	gameState.pieces[gameState.current-1] = gameState.pieces[gameState.current-1].andNot(cell)
+	gameState.mask = gameState.mask.andNot(cell)
+	gameState.hash ^= layout.zobristPiece(Piece(gameState.current), x, gameState.heights[x])
+
+	// Nothing is played after the game ends, so undoing any move reopens it
+	gameState.winner = 0
+	gameState.over = false
+
+	return nil
+}
+
+// History returns the moves made in this game, oldest first.
+func (gameState *MultiGameState) History() []Move {
+	history := make([]Move, len(gameState.history))
+	copy(history, gameState.history)
+
+	return history
+}
+
+// Hash returns the 64-bit Zobrist hash of the position and player to move.
+func (gameState *MultiGameState) Hash() uint64 {
+	return gameState.hash
+}
+
+func (gameState *MultiGameState) computeHash() uint64 {
+	layout := gameState.layout
+	hash := layout.zobristTurns[gameState.current-1]
+
+	for x := 0; x < layout.rules.Width; x++ {
+		for row := 0; row < gameState.heights[x]; row++ {
+			if piece := gameState.pieceAt(x, row); piece != EmptyPiece {
+				hash ^= layout.zobristPiece(piece, x, row)
+			}
+		}
+	}
+
+	return hash
+}
+
+func (gameState *MultiGameState) pieceAt(x int, row int) Piece {
+	cell := gameState.layout.cellMask(x, row)
+	for player, pieces := range gameState.pieces {
+		if pieces.intersects(cell) {
+			return Piece(player + 1)
+		}
+	}
+
+	return EmptyPiece
+}
+
+// PlayerPieces returns how many pieces player has on the board.
+func (gameState *MultiGameState) PlayerPieces(player PlayerID) int {
+	return gameState.pieces[player-1].count()
+}
+
+func (gameState *MultiGameState) GetBoard() *Board {
+	rules := gameState.layout.rules
+	board := NewBoard(rules.Width, rules.Height)
+
+	for x := 0; x < rules.Width; x++ {
+		for row := 0; row < rules.Height; row++ {
+			(*board)[rules.Height-1-row][x] = gameState.pieceAt(x, row)
+		}
+	}
+
+	return board
+}
+
+// WinningLines returns the winner's lines, as GameState.WinningLines does.
+func (gameState *MultiGameState) WinningLines() []WinningLine {
+	if gameState.winner == 0 {
+		return nil
+	}
+
+	return gameState.layout.findLines(gameState.pieces[gameState.winner-1])
+}
+
+func (gameState *MultiGameState) Clone() *MultiGameState {
+	pieces := make([]bitboard, len(gameState.pieces))
+	copy(pieces, gameState.pieces)
+	heights := make([]int, len(gameState.heights))
+	copy(heights, gameState.heights)
+
+	return &MultiGameState{
+		layout:  gameState.layout,
+		pieces:  pieces,
+		mask:    gameState.mask,
+		heights: heights,
+		current: gameState.current,
+		winner:  gameState.winner,
+		over:    gameState.over,
+		hash:    gameState.hash,
+		history: gameState.History(),
+	}
+}
+
+func (gameState *MultiGameState) String() string {
+	output := gameState.GetBoard().String()
+	if gameState.winner != 0 {
+		output += fmt.Sprintf("Game Over - Player %d Won!\n", gameState.winner)
+	} else if gameState.over {
+		output += "Game Over - Draw!\n"
+	} else {
+		output += fmt.Sprintf("Player %d's turn.\n", gameState.current)
+	}
+
+	return output
+}
+
+func (gameState *MultiGameState) Print() {
+	print(gameState.String())
+}
+
+func (gameState *MultiGameState) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(gameState.String())
+	return err
+}
+
+// ParseMultiGame reads a game in the GameState file format, with the third
+// and later players as G, B, P and O. The player to move comes from the piece
+// counts, which must be possible in turn order.
+func ParseMultiGame(gameDescription string, rules Rules) (*MultiGameState, error) {
+	board, err := parseBoard(gameDescription)
+	if err != nil {
+		return nil, err
+	}
+
+	gameState, err := NewMultiGame(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if board.Width() != rules.Width || board.Height() != rules.Height {
+		return nil, fmt.Errorf("%w: %dx%d board, expected %dx%d", ErrInvalidGameDescription, board.Width(), board.Height(), rules.Width, rules.Height)
+	}
+
+	layout := gameState.layout
+	var problems []ValidationProblem
+	for x := 0; x < rules.Width; x++ {
+		for row := 0; row < rules.Height; row++ {
+			cell := Cell{x, rules.Height - 1 - row}
+			piece := (*board)[cell.Y][x]
+			if piece == EmptyPiece {
+				continue
+			}
+
+			if piece < EmptyPiece || int(piece) > gameState.Players() {
+				problems = append(problems, ValidationProblem{ErrUnknownPiece, []Cell{cell}})
+				continue
+			}
+
+			if gameState.heights[x] != row {
+				problems = append(problems, ValidationProblem{ErrFloatingPiece, []Cell{cell}})
+			}
+
+			gameState.pieces[piece-1] = gameState.pieces[piece-1].or(layout.cellMask(x, row))
+			gameState.mask = gameState.mask.or(layout.cellMask(x, row))
+			gameState.heights[x] = row + 1
+		}
+	}
+
+	// Earlier players have made one more move than later ones, or the same
+	counts := make([]int, gameState.Players())
+	for player := range counts {
+		counts[player] = gameState.pieces[player].count()
+	}
+	for player := 1; player < len(counts); player++ {
+		if counts[player] > counts[player-1] || counts[player] < counts[0]-1 {
+			problems = append(problems, ValidationProblem{Err: fmt.Errorf("%w: player %d has %d pieces, player %d has %d", ErrPieceCount, player, counts[player-1], player+1, counts[player])})
+			break
+		}
+		if counts[player] < counts[player-1] {
+			gameState.current = PlayerID(player + 1)
+		}
+	}
+
+	if turn := parseTurn(gameDescription); turn != EmptyPiece && PlayerID(turn) != gameState.current {
+		problems = append(problems, ValidationProblem{Err: ErrWrongTurn})
+	}
+
+	for player, pieces := range gameState.pieces {
+		if !layout.hasConnect(pieces) {
+			continue
+		}
+
+		if gameState.winner != 0 {
+			problems = append(problems, ValidationProblem{ErrBothPlayersWon, lineCells(append(layout.findLines(gameState.pieces[gameState.winner-1]), layout.findLines(pieces)...))})
+			break
+		}
+		gameState.winner = PlayerID(player + 1)
+		gameState.over = true
+	}
+
+	if gameState.winner != 0 && gameState.previousPlayer(gameState.current) != gameState.winner {
+		problems = append(problems, ValidationProblem{ErrPlayAfterWin, lineCells(gameState.WinningLines())})
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{problems}
+	}
+
+	if gameState.mask == layout.boardMask {
+		gameState.over = true
+	}
+	gameState.hash = gameState.computeHash()
+
+	return gameState, nil
+}
+
+func LoadMultiGame(filename string, rules Rules) (*MultiGameState, error) {
+	gameDescriptionBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMultiGame(string(gameDescriptionBytes), rules)
+}
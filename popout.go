@@ -0,0 +1,84 @@
+package connect4
+
+// repetitionLimit is how many times a position may occur under PopOut before
+// the game is drawn.
+const repetitionLimit = 3
+
+func (gameState *GameState) initPositionCounts() {
+	if gameState.layout.rules.PopOut {
+		gameState.positionCounts = map[uint64]int{gameState.hash: 1}
+	}
+}
+
+func (gameState *GameState) clonePositionCounts() map[uint64]int {
+	if gameState.positionCounts == nil {
+		return nil
+	}
+
+	positionCounts := make(map[uint64]int, len(gameState.positionCounts))
+	for hash, count := range gameState.positionCounts {
+		positionCounts[hash] = count
+	}
+
+	return positionCounts
+}
+
+// columnHashes returns the hash and mirror hash contributions of column x.
+func (gameState *GameState) columnHashes(x int) (uint64, uint64) {
+	layout := gameState.layout
+	player1Pieces := gameState.getPlayerPieces(Player1Piece)
+	mirrorX := layout.rules.Width - 1 - x
+
+	var hash, mirrorHash uint64
+	for row := 0; row < gameState.heights[x]; row++ {
+		cell := layout.cellMask(x, row)
+		if !gameState.mask.intersects(cell) {
+			continue
+		}
+
+		piece := Player2Piece
+		if gameState.neutral.intersects(cell) {
+			piece = NeutralPiece
+		} else if player1Pieces.intersects(cell) {
+			piece = Player1Piece
+		}
+		hash ^= layout.zobristPiece(piece, x, row)
+		mirrorHash ^= layout.zobristPiece(piece, mirrorX, row)
+	}
+
+	return hash, mirrorHash
+}
+
+// popPiece removes the bottom piece of column x and lets the rest fall. It
+// must be called while current still holds the popping player's pieces.
+func (gameState *GameState) popPiece(x int) {
+	column := gameState.layout.columnMask(x)
+	oldHash, oldMirrorHash := gameState.columnHashes(x)
+
+	gameState.current = gameState.current.andNot(column).or(gameState.current.and(column).shr(1).and(column))
+	gameState.mask = gameState.mask.andNot(column).or(gameState.mask.and(column).shr(1).and(column))
+	gameState.neutral = gameState.neutral.andNot(column).or(gameState.neutral.and(column).shr(1).and(column))
+	gameState.heights[x]--
+
+	newHash, newMirrorHash := gameState.columnHashes(x)
+	gameState.hash ^= oldHash ^ newHash
+	gameState.mirrorHash ^= oldMirrorHash ^ newMirrorHash
+}
+
+// unpopPiece reverses popPiece, putting a piece of the player whose pieces
+// are in current back at the bottom of column x.
+func (gameState *GameState) unpopPiece(x int) {
+	layout := gameState.layout
+	column := layout.columnMask(x)
+	bottom := layout.cellMask(x, 0)
+	oldHash, oldMirrorHash := gameState.columnHashes(x)
+
+	gameState.current = gameState.current.andNot(column).or(gameState.current.and(column).shl(1).and(column)).or(bottom)
+	gameState.mask = gameState.mask.andNot(column).or(gameState.mask.and(column).shl(1).and(column)).or(bottom)
+	gameState.neutral = gameState.neutral.andNot(column).or(gameState.neutral.and(column).shl(1).and(column))
+	gameState.heights[x]++
+
+	newHash, newMirrorHash := gameState.columnHashes(x)
+	gameState.hash ^= oldHash ^ newHash
+	gameState.mirrorHash ^= oldMirrorHash ^ newMirrorHash
+}
@@ -0,0 +1,135 @@
+package connect4
+
+// zobristSeed is fixed so hashes are stable between runs and can be stored.
+const zobristSeed uint64 = 0x436f6e6e65637434
+
+// splitMix64 is used only to fill the Zobrist tables deterministically.
+type splitMix64 uint64
+
+func (state *splitMix64) next() uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := uint64(*state)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (layout *layout) initZobrist() {
+	random := splitMix64(zobristSeed)
+	bitCount := uint(layout.rules.Width) * layout.columnStride
+	players := layout.rules.PlayerCount()
+
+	// Tables for players after the second are drawn last, so two player
+	// hashes are the same whatever the player count
+	layout.zobristPieces = make([][]uint64, players)
+	fillPieces := func(piece int) {
+		layout.zobristPieces[piece] = make([]uint64, bitCount)
+		for i := range layout.zobristPieces[piece] {
+			layout.zobristPieces[piece][i] = random.next()
+		}
+	}
+
+	fillPieces(0)
+	fillPieces(1)
+	layout.zobristSide = random.next()
+
+	layout.zobristTurns = []uint64{0, layout.zobristSide}
+	for piece := 2; piece < players; piece++ {
+		fillPieces(piece)
+		layout.zobristTurns = append(layout.zobristTurns, random.next())
+	}
+
+	layout.zobristNeutral = make([]uint64, bitCount)
+	for i := range layout.zobristNeutral {
+		layout.zobristNeutral[i] = random.next()
+	}
+}
+
+func (layout *layout) bitIndex(x int, row int) uint {
+	return uint(x)*layout.columnStride + uint(row)
+}
+
+// zobristPiece is the hash contribution of piece at column x, row (from the bottom).
+func (layout *layout) zobristPiece(piece Piece, x int, row int) uint64 {
+	if piece == NeutralPiece {
+		return layout.zobristNeutral[layout.bitIndex(x, row)]
+	}
+
+	return layout.zobristPieces[piece-Player1Piece][layout.bitIndex(x, row)]
+}
+
+func (gameState *GameState) computeHash() uint64 {
+	return gameState.layout.positionHash(gameState.getPlayerPieces(Player1Piece), gameState.mask, gameState.neutral, gameState.currentPiece)
+}
+
+// positionHash computes the Zobrist hash of a position from scratch.
+func (layout *layout) positionHash(player1Pieces bitboard, mask bitboard, neutral bitboard, currentPiece Piece) uint64 {
+	var hash uint64
+
+	for x := 0; x < layout.rules.Width; x++ {
+		for row := 0; row < layout.rules.Height; row++ {
+			cell := layout.cellMask(x, row)
+			if !mask.intersects(cell) {
+				continue
+			}
+
+			if neutral.intersects(cell) {
+				hash ^= layout.zobristPiece(NeutralPiece, x, row)
+			} else if player1Pieces.intersects(cell) {
+				hash ^= layout.zobristPiece(Player1Piece, x, row)
+			} else {
+				hash ^= layout.zobristPiece(Player2Piece, x, row)
+			}
+		}
+	}
+
+	if currentPiece == Player2Piece {
+		hash ^= layout.zobristSide
+	}
+
+	return hash
+}
+
+// Hash returns the 64-bit Zobrist hash of the position and side to move. It is
+// updated incrementally by MakeMove, UndoMove and RedoMove.
+func (gameState *GameState) Hash() uint64 {
+	return gameState.hash
+}
+
+// Key returns a compact encoding of the position: player 1's pieces plus a
+// marker bit on top of each column. Under PopOut, from a setup, or parsed with
+// a turn the piece counts don't give, the side to move doesn't follow from the
+// counts, so it goes in the top bit. Keys never collide when Width*(Height+1)
+// is at most 64, or 63 with the side bit, which includes the standard board.
+// Larger boards fold the upper bits in, or fall back to Hash when the side is
+// needed, so use Hash for those. Gravity-free games and games with neutral
+// pieces get a Zobrist hash too.
+func (gameState *GameState) Key() uint64 {
+	return gameState.layout.positionKey(gameState.getPlayerPieces(Player1Piece), gameState.mask, gameState.neutral, gameState.keySide())
+}
+
+// keySide returns the side to move for positionKey, or EmptyPiece if the
+// piece counts already tell.
+func (gameState *GameState) keySide() Piece {
+	if gameState.layout.rules.PopOut || gameState.fromSetup || gameState.sideGiven {
+		return gameState.currentPiece
+	}
+
+	return EmptyPiece
+}
+
+func (layout *layout) positionKey(player1Pieces bitboard, mask bitboard, neutral bitboard, side Piece) uint64 {
+	// Without gravity columns can have gaps, and neutral pieces are a third
+	// kind, neither of which the encoding can describe
+	bits := uint(layout.rules.Width) * layout.columnStride
+	if layout.rules.GravityFree || !neutral.isZero() || (side != EmptyPiece && bits >= 64) {
+		return layout.positionHash(player1Pieces, mask, neutral, side)
+	}
+
+	key := player1Pieces.add(mask).add(layout.bottomMask)
+	if side == Player2Piece {
+		return key.lo | 1<<63
+	}
+
+	return key.lo ^ key.hi
+}
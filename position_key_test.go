@@ -0,0 +1,56 @@
+package connect4
+
+import "testing"
+
+func TestKeyIncludesSideWhenCountsDontTellIt(t *testing.T) {
+	popped, err := NewGameWithRules(PopOutRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, move := range []Move{DropMove(0), DropMove(1), PopMove(0)} {
+		if err := popped.MakeMove(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The same yellow piece, with red to move
+	board := *NewBoard(PopOutRules.Width, PopOutRules.Height)
+	board[PopOutRules.Height-1][1] = Player2Piece
+	parsed, err := ParseGameWithOptions(board.String()+"\nPlayer 1's turn.", ParseOptions{Rules: PopOutRules, Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if popped.Key() == parsed.Key() {
+		t.Errorf("popped game with yellow to move and parsed game with red to move share key %x", popped.Key())
+	}
+
+	setup := *NewBoard(7, 6)
+	setup[5][2] = Player1Piece
+	var keys [2]uint64
+	for i, toMove := range []PlayerID{Player1, Player2} {
+		gameState, err := NewGameFromSetup(StandardRules, &setup, toMove)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = gameState.Key()
+	}
+	if keys[0] == keys[1] {
+		t.Errorf("setups with either player to move share key %x", keys[0])
+	}
+
+	// Lenient parsing takes the turn line over the counts
+	lenient := *NewBoard(7, 6)
+	lenient[5][2] = Player1Piece
+	lenient[5][3] = Player2Piece
+	for i, turn := range []string{"Player 1's turn.", "Player 2's turn."} {
+		gameState, err := ParseGameWithOptions(lenient.String()+"\n"+turn, ParseOptions{Rules: StandardRules, Lenient: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = gameState.Key()
+	}
+	if keys[0] == keys[1] {
+		t.Errorf("lenient parses with either player to move share key %x", keys[0])
+	}
+}
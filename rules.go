@@ -0,0 +1,155 @@
+package connect4
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Rules struct {
+	Width         int
+	Height        int
+	ConnectLength int
+	// PopOut lets a player remove one of their own pieces from the bottom of
+	// a column instead of dropping one. If a pop completes lines for both
+	// players the popping player loses, and a position repeated three times
+	// is a draw.
+	PopOut bool
+	// Misere makes completing a line lose instead of win. Threat and
+	// zugzwang analysis still describe lines, not results.
+	Misere bool
+	// Cylinder joins the left and right edges, so horizontal and diagonal
+	// lines can run off one side of the board and on at the other.
+	Cylinder bool
+	// GravityFree lets a piece go in any empty cell, named with CellMove,
+	// instead of dropping to the bottom of a column.
+	GravityFree bool
+	// Players is the number of players taking turns, with zero meaning two.
+	// Games for more than two are played with MultiGameState.
+	Players int
+}
+
+var PopOutRules = Rules{Width: BoardWidth, Height: BoardHeight, ConnectLength: 4, PopOut: true}
+
+var CylinderRules = Rules{Width: BoardWidth, Height: BoardHeight, ConnectLength: 4, Cylinder: true}
+
+var GravityFreeRules = Rules{Width: BoardWidth, Height: BoardHeight, ConnectLength: 4, GravityFree: true}
+
+var MisereRules = Rules{Width: BoardWidth, Height: BoardHeight, ConnectLength: 4, Misere: true}
+
+var StandardRules = Rules{Width: BoardWidth, Height: BoardHeight, ConnectLength: 4}
+
+func (rules Rules) Validate() error {
+	if rules.Width < 1 || rules.Height < 1 {
+		return fmt.Errorf("%w: board size %dx%d", ErrInvalidRules, rules.Width, rules.Height)
+	}
+
+	if rules.Width*(rules.Height+1) > maxBitboardBits {
+		return fmt.Errorf("%w: %dx%d board needs more than %d bits", ErrInvalidRules, rules.Width, rules.Height, maxBitboardBits)
+	}
+
+	if rules.ConnectLength < 2 || (rules.ConnectLength > rules.Width && rules.ConnectLength > rules.Height) {
+		return fmt.Errorf("%w: connect length %d on a %dx%d board", ErrInvalidRules, rules.ConnectLength, rules.Width, rules.Height)
+	}
+
+	players := rules.PlayerCount()
+	if players < 2 || players > maxPlayers {
+		return fmt.Errorf("%w: %d players", ErrInvalidRules, players)
+	}
+
+	if players > 2 && (rules.PopOut || rules.Misere || rules.GravityFree) {
+		return fmt.Errorf("%w: %d players with %s", ErrInvalidRules, players, rules)
+	}
+
+	if rules.PopOut && rules.GravityFree {
+		return fmt.Errorf("%w: popout needs gravity", ErrInvalidRules)
+	}
+
+	return nil
+}
+
+// PlayerCount returns the number of players, filling in the default of two.
+func (rules Rules) PlayerCount() int {
+	if rules.Players == 0 {
+		return 2
+	}
+
+	return rules.Players
+}
+
+// ParseMove reads a column number to drop in, or a column number prefixed
+// with 'p' to pop under PopOut rules. Under GravityFree rules it reads an
+// "x,y" cell instead, with y counting down from the top row.
+func (rules Rules) ParseMove(moveString string) (Move, error) {
+	if rules.GravityFree || strings.Contains(moveString, ",") {
+		return rules.parseCellMove(moveString)
+	}
+
+	isPop := strings.HasPrefix(moveString, "p") || strings.HasPrefix(moveString, "P")
+	if isPop {
+		moveString = moveString[1:]
+	}
+
+	column, err := strconv.Atoi(moveString)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMoveString, err)
+	}
+
+	move := DropMove(column)
+	if isPop {
+		move = PopMove(column)
+	}
+
+	if column < 0 || column >= rules.Width {
+		return 0, &MoveError{move, ErrColumnOutOfRange}
+	}
+
+	if isPop && !rules.PopOut {
+		return 0, &MoveError{move, ErrMoveNotAllowed}
+	}
+
+	return move, nil
+}
+
+func (rules Rules) parseCellMove(moveString string) (Move, error) {
+	var cell Cell
+	if _, err := fmt.Sscanf(moveString, "%d,%d", &cell.X, &cell.Y); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMoveString, err)
+	}
+
+	move := CellMove(cell)
+	if cell.X < 0 || cell.X >= rules.Width {
+		return 0, &MoveError{move, ErrColumnOutOfRange}
+	}
+
+	if cell.Y < 0 || cell.Y >= rules.Height {
+		return 0, &MoveError{move, ErrRowOutOfRange}
+	}
+
+	if !rules.GravityFree {
+		return 0, &MoveError{move, ErrMoveNotAllowed}
+	}
+
+	return move, nil
+}
+
+func (rules Rules) String() string {
+	description := fmt.Sprintf("%dx%d connect %d", rules.Width, rules.Height, rules.ConnectLength)
+	if rules.PopOut {
+		description += " popout"
+	}
+	if rules.Misere {
+		description += " misere"
+	}
+	if rules.Cylinder {
+		description += " cylinder"
+	}
+	if rules.GravityFree {
+		description += " gravity-free"
+	}
+	if rules.PlayerCount() != 2 {
+		description += fmt.Sprintf(" %d players", rules.PlayerCount())
+	}
+
+	return description
+}
@@ -0,0 +1,549 @@
+package connect4
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"os"
+	"strings"
+)
+
+/* Score Four is connect four in three dimensions: players drop beads onto
+ * the 16 pegs of a 4x4 base, each peg holding four, and the first to make a
+ * line of four in any of the 13 directions through the cube wins. Moves are
+ * pegs, made with PegMove, and are played through the same methods as a
+ * GameState.
+ *
+ * The position fits in a uint64 per player, peg by peg with the bottom bead
+ * first: bit (y*4 + x)*4 + level.
+ */
+
+const ScoreFourSize = 4
+
+const scoreFourPegs = ScoreFourSize * ScoreFourSize
+
+// ScoreFourBoard is indexed [level][y][x], with level 0 at the bottom.
+type ScoreFourBoard [ScoreFourSize][ScoreFourSize][ScoreFourSize]Piece
+
+// PegMove drops a bead on the peg at x, y.
+func PegMove(x int, y int) Move {
+	return Move(y*ScoreFourSize + x)
+}
+
+// Peg returns the x, y of a Score Four move.
+func (move Move) Peg() (int, int) {
+	return move.Column() % ScoreFourSize, move.Column() / ScoreFourSize
+}
+
+// ParsePegMove reads a Score Four move written as "x,y".
+func ParsePegMove(moveString string) (Move, error) {
+	var x, y int
+	if _, err := fmt.Sscanf(moveString, "%d,%d", &x, &y); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMoveString, err)
+	}
+
+	if x < 0 || x >= ScoreFourSize || y < 0 || y >= ScoreFourSize {
+		return 0, &MoveError{PegMove(x, y), ErrColumnOutOfRange}
+	}
+
+	return PegMove(x, y), nil
+}
+
+type scoreFourGeometry struct {
+	lines []uint64
+	// cellLines lists the lines through each cell, by bit index
+	cellLines     [scoreFourPegs * ScoreFourSize][]uint64
+	zobristPieces [2][scoreFourPegs * ScoreFourSize]uint64
+	zobristSide   uint64
+}
+
+var scoreFour = newScoreFourGeometry()
+
+func scoreFourBit(x int, y int, level int) uint64 {
+	return 1 << uint((y*ScoreFourSize+x)*ScoreFourSize+level)
+}
+
+func newScoreFourGeometry() *scoreFourGeometry {
+	geometry := &scoreFourGeometry{}
+
+	// Every direction with its first non-zero step positive, 13 in all
+	var directions [][3]int
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dLevel := -1; dLevel <= 1; dLevel++ {
+				if dx > 0 || (dx == 0 && dy > 0) || (dx == 0 && dy == 0 && dLevel > 0) {
+					directions = append(directions, [3]int{dx, dy, dLevel})
+				}
+			}
+		}
+	}
+
+	inRange := func(i int) bool {
+		return i >= 0 && i < ScoreFourSize
+	}
+
+	for _, direction := range directions {
+		for x := 0; x < ScoreFourSize; x++ {
+			for y := 0; y < ScoreFourSize; y++ {
+				for level := 0; level < ScoreFourSize; level++ {
+					endX := x + (ScoreFourSize-1)*direction[0]
+					endY := y + (ScoreFourSize-1)*direction[1]
+					endLevel := level + (ScoreFourSize-1)*direction[2]
+					if !inRange(endX) || !inRange(endY) || !inRange(endLevel) {
+						continue
+					}
+
+					var line uint64
+					for i := 0; i < ScoreFourSize; i++ {
+						line |= scoreFourBit(x+i*direction[0], y+i*direction[1], level+i*direction[2])
+					}
+					geometry.lines = append(geometry.lines, line)
+				}
+			}
+		}
+	}
+
+	for _, line := range geometry.lines {
+		for cells := line; cells != 0; cells &= cells - 1 {
+			index := bits.TrailingZeros64(cells)
+			geometry.cellLines[index] = append(geometry.cellLines[index], line)
+		}
+	}
+
+	// A seed of its own keeps these apart from connect four hashes
+	random := splitMix64(zobristSeed ^ 0x53636f7265342121)
+	for piece := range geometry.zobristPieces {
+		for i := range geometry.zobristPieces[piece] {
+			geometry.zobristPieces[piece][i] = random.next()
+		}
+	}
+	geometry.zobristSide = random.next()
+
+	return geometry
+}
+
+type ScoreFourGameState struct {
+	pieces  [2]uint64 // Indexed by piece - Player1Piece
+	heights [scoreFourPegs]int
+	turn    Turn
+	hash    uint64
+	history []Move
+}
+
+func NewScoreFourGame() *ScoreFourGameState {
+	return &ScoreFourGameState{turn: Player1Turn}
+}
+
+func (gameState *ScoreFourGameState) GetTurn() Turn {
+	return gameState.turn
+}
+
+func (gameState *ScoreFourGameState) IsGameOver() bool {
+	return gameState.turn != Player1Turn && gameState.turn != Player2Turn
+}
+
+func (gameState *ScoreFourGameState) currentPiece() Piece {
+	if gameState.turn == Player2Turn {
+		return Player2Piece
+	}
+
+	return Player1Piece
+}
+
+// currentPlayer counts pieces rather than reading the turn, which doesn't
+// say once the game is over.
+func (gameState *ScoreFourGameState) currentPlayer() PlayerID {
+	if bits.OnesCount64(gameState.pieces[0]) > bits.OnesCount64(gameState.pieces[1]) {
+		return Player2
+	}
+
+	return Player1
+}
+
+func (gameState *ScoreFourGameState) IsValidMove(move Move) bool {
+	return gameState.checkMove(move) == nil
+}
+
+func (gameState *ScoreFourGameState) checkMove(move Move) error {
+	if gameState.IsGameOver() {
+		return &MoveError{move, ErrGameOver}
+	}
+
+	if !move.isWellFormed() || move.Column() >= scoreFourPegs {
+		return &MoveError{move, ErrColumnOutOfRange}
+	}
+
+	if move.IsPop() || move.IsCell() {
+		return &MoveError{move, ErrMoveNotAllowed}
+	}
+
+	if gameState.heights[move.Column()] >= ScoreFourSize {
+		return &MoveError{move, ErrColumnFull}
+	}
+
+	return nil
+}
+
+func (gameState *ScoreFourGameState) GetPossibleMoves() []Move {
+	moves := make([]Move, 0, scoreFourPegs)
+	if gameState.IsGameOver() {
+		return moves
+	}
+
+	for peg, height := range gameState.heights {
+		if height < ScoreFourSize {
+			moves = append(moves, Move(peg))
+		}
+	}
+
+	return moves
+}
+
+func (gameState *ScoreFourGameState) MakeMove(move Move) error {
+	if err := gameState.checkMove(move); err != nil {
+		return err
+	}
+
+	gameState.playMove(move)
+
+	return nil
+}
+
+// playMove makes a move already known to be valid.
+func (gameState *ScoreFourGameState) playMove(move Move) {
+	piece := gameState.currentPiece()
+	x, y := move.Peg()
+	bit := scoreFourBit(x, y, gameState.heights[move.Column()])
+	index := bits.TrailingZeros64(bit)
+
+	gameState.pieces[piece-Player1Piece] |= bit
+	gameState.heights[move.Column()]++
+	gameState.hash ^= scoreFour.zobristPieces[piece-Player1Piece][index] ^ scoreFour.zobristSide
+	gameState.history = append(gameState.history, move)
+
+	if hasScoreFourLine(gameState.pieces[piece-Player1Piece], scoreFour.cellLines[index]) {
+		if piece == Player1Piece {
+			gameState.turn = Player1Won
+		} else {
+			gameState.turn = Player2Won
+		}
+	} else if gameState.pieceCount() == scoreFourPegs*ScoreFourSize {
+		gameState.turn = Draw
+	} else if piece == Player1Piece {
+		gameState.turn = Player2Turn
+	} else {
+		gameState.turn = Player1Turn
+	}
+}
+
+func (gameState *ScoreFourGameState) pieceCount() int {
+	return bits.OnesCount64(gameState.pieces[0] | gameState.pieces[1])
+}
+
+func hasScoreFourLine(pieces uint64, lines []uint64) bool {
+	for _, line := range lines {
+		if pieces&line == line {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (gameState *ScoreFourGameState) UndoMove() error {
+	if len(gameState.history) == 0 {
+		return ErrNothingToUndo
+	}
+
+	gameState.unplayMove()
+
+	return nil
+}
+
+// unplayMove takes back the last move, which there must be, and returns it.
+func (gameState *ScoreFourGameState) unplayMove() Move {
+	move := gameState.history[len(gameState.history)-1]
+	gameState.history = gameState.history[:len(gameState.history)-1]
+
+	// Player 1 moves first, so has more pieces only after their own move
+	piece := Player2Piece
+	if bits.OnesCount64(gameState.pieces[0]) > bits.OnesCount64(gameState.pieces[1]) {
+		piece = Player1Piece
+	}
+
+	gameState.heights[move.Column()]--
+	x, y := move.Peg()
+	bit := scoreFourBit(x, y, gameState.heights[move.Column()])
+	gameState.pieces[piece-Player1Piece] &^= bit
+	gameState.hash ^= scoreFour.zobristPieces[piece-Player1Piece][bits.TrailingZeros64(bit)] ^ scoreFour.zobristSide
+
+	if piece == Player1Piece {
+		gameState.turn = Player1Turn
+	} else {
+		gameState.turn = Player2Turn
+	}
+
+	return move
+}
+
+// History returns the moves made in this game, oldest first.
+func (gameState *ScoreFourGameState) History() []Move {
+	history := make([]Move, len(gameState.history))
+	copy(history, gameState.history)
+
+	return history
+}
+
+// Hash returns the 64-bit Zobrist hash of the position and side to move.
+func (gameState *ScoreFourGameState) Hash() uint64 {
+	return gameState.hash
+}
+
+func (gameState *ScoreFourGameState) Clone() *ScoreFourGameState {
+	clone := *gameState
+	clone.history = gameState.History()
+
+	return &clone
+}
+
+func (gameState *ScoreFourGameState) searchClone() searchGame {
+	return gameState.Clone()
+}
+
+func (gameState *ScoreFourGameState) emptyCells() int {
+	return scoreFourPegs*ScoreFourSize - gameState.pieceCount()
+}
+
+// centreDistance puts the four middle pegs first, as they are in the most lines.
+func (gameState *ScoreFourGameState) centreDistance(move Move) int {
+	x, y := move.Peg()
+	return centreDistance(x, ScoreFourSize) + centreDistance(y, ScoreFourSize)
+}
+
+func (gameState *ScoreFourGameState) GetBoard() *ScoreFourBoard {
+	board := &ScoreFourBoard{}
+	for level := 0; level < ScoreFourSize; level++ {
+		for y := 0; y < ScoreFourSize; y++ {
+			for x := 0; x < ScoreFourSize; x++ {
+				bit := scoreFourBit(x, y, level)
+				if gameState.pieces[0]&bit != 0 {
+					board[level][y][x] = Player1Piece
+				} else if gameState.pieces[1]&bit != 0 {
+					board[level][y][x] = Player2Piece
+				}
+			}
+		}
+	}
+
+	return board
+}
+
+// WinningLines returns the winner's lines as the cells of each, in x, y,
+// level order.
+func (gameState *ScoreFourGameState) WinningLines() [][][3]int {
+	var pieces uint64
+	switch gameState.turn {
+	case Player1Won:
+		pieces = gameState.pieces[0]
+	case Player2Won:
+		pieces = gameState.pieces[1]
+	default:
+		return nil
+	}
+
+	var lines [][][3]int
+	for _, line := range scoreFour.lines {
+		if pieces&line != line {
+			continue
+		}
+
+		var cells [][3]int
+		for bitsLeft := line; bitsLeft != 0; bitsLeft &= bitsLeft - 1 {
+			index := bits.TrailingZeros64(bitsLeft)
+			peg := index / ScoreFourSize
+			cells = append(cells, [3]int{peg % ScoreFourSize, peg / ScoreFourSize, index % ScoreFourSize})
+		}
+		lines = append(lines, cells)
+	}
+
+	return lines
+}
+
+/* Score Four File Format: each level as a 4x4 grid in the GameState file
+ * format, x across and y down, under a "Level N:" line. The top level comes
+ * first, so the text reads like looking down on the pegs layer by layer.
+ */
+
+func (board ScoreFourBoard) String() string {
+	var output string
+	for level := ScoreFourSize - 1; level >= 0; level-- {
+		levelBoard := NewBoard(ScoreFourSize, ScoreFourSize)
+		for y := 0; y < ScoreFourSize; y++ {
+			copy((*levelBoard)[y], board[level][y][:])
+		}
+
+		output += fmt.Sprintf("Level %d:\n", level)
+		output += levelBoard.String()
+	}
+
+	return output
+}
+
+func (gameState *ScoreFourGameState) String() string {
+	output := gameState.GetBoard().String()
+	switch gameState.turn {
+	case Draw:
+		output += "Game Over - Draw!\n"
+	case Player1Turn:
+		output += "Player 1's turn.\n"
+	case Player2Turn:
+		output += "Player 2's turn.\n"
+	case Player1Won:
+		output += "Game Over - Player 1 Won!\n"
+	case Player2Won:
+		output += "Game Over - Player 2 Won!\n"
+	default:
+		output += "Invalid Turn!\n"
+	}
+	return output
+}
+
+func (gameState *ScoreFourGameState) Print() {
+	print(gameState.String())
+}
+
+func (gameState *ScoreFourGameState) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(gameState.String())
+	return err
+}
+
+func parseScoreFourBoard(gameDescription string) (*ScoreFourBoard, error) {
+	board := &ScoreFourBoard{}
+	seen := make([]bool, ScoreFourSize)
+
+	sections := strings.Split(gameDescription, "Level ")
+	for _, section := range sections[1:] {
+		var level int
+		if _, err := fmt.Sscanf(section, "%d:", &level); err != nil || level < 0 || level >= ScoreFourSize || seen[level] {
+			return nil, fmt.Errorf("%w: bad level header 'Level %.2s'", ErrInvalidGameDescription, section)
+		}
+		seen[level] = true
+
+		levelBoard, err := parseBoard(section)
+		if err != nil {
+			return nil, err
+		}
+		if levelBoard.Width() != ScoreFourSize || levelBoard.Height() != ScoreFourSize {
+			return nil, fmt.Errorf("%w: level %d is %dx%d", ErrInvalidGameDescription, level, levelBoard.Width(), levelBoard.Height())
+		}
+
+		for y := 0; y < ScoreFourSize; y++ {
+			copy(board[level][y][:], (*levelBoard)[y])
+		}
+	}
+
+	for level, found := range seen {
+		if !found {
+			return nil, fmt.Errorf("%w: level %d missing", ErrInvalidGameDescription, level)
+		}
+	}
+
+	return board, nil
+}
+
+// ParseScoreFourGame reads a game written by ScoreFourGameState.String. The
+// position must be reachable in play, and the turn follows from the counts.
+func ParseScoreFourGame(gameDescription string) (*ScoreFourGameState, error) {
+	board, err := parseScoreFourBoard(gameDescription)
+	if err != nil {
+		return nil, err
+	}
+
+	gameState := NewScoreFourGame()
+	var problems []ValidationProblem
+	for level := 0; level < ScoreFourSize; level++ {
+		for y := 0; y < ScoreFourSize; y++ {
+			for x := 0; x < ScoreFourSize; x++ {
+				piece := board[level][y][x]
+				switch piece {
+				case EmptyPiece:
+					continue
+				case Player1Piece, Player2Piece:
+				default:
+					problems = append(problems, ValidationProblem{Err: fmt.Errorf("%w at %d,%d level %d", ErrUnknownPiece, x, y, level)})
+					continue
+				}
+
+				peg := y*ScoreFourSize + x
+				if gameState.heights[peg] != level {
+					problems = append(problems, ValidationProblem{Err: fmt.Errorf("%w at %d,%d level %d", ErrFloatingPiece, x, y, level)})
+				}
+				gameState.heights[peg] = level + 1
+
+				bit := scoreFourBit(x, y, level)
+				gameState.pieces[piece-Player1Piece] |= bit
+				gameState.hash ^= scoreFour.zobristPieces[piece-Player1Piece][bits.TrailingZeros64(bit)]
+			}
+		}
+	}
+
+	player1PieceCount := bits.OnesCount64(gameState.pieces[0])
+	player2PieceCount := bits.OnesCount64(gameState.pieces[1])
+	if player1PieceCount != player2PieceCount && player1PieceCount != player2PieceCount+1 {
+		problems = append(problems, ValidationProblem{Err: fmt.Errorf("%w: %d red pieces, %d yellow pieces", ErrPieceCount, player1PieceCount, player2PieceCount)})
+	}
+
+	toMove := Player1Piece
+	if player1PieceCount != player2PieceCount {
+		toMove = Player2Piece
+	}
+	if turn := parseTurn(gameDescription); turn != EmptyPiece && turn != toMove {
+		problems = append(problems, ValidationProblem{Err: ErrWrongTurn})
+	}
+
+	player1Won := hasScoreFourLine(gameState.pieces[0], scoreFour.lines)
+	player2Won := hasScoreFourLine(gameState.pieces[1], scoreFour.lines)
+	if player1Won && player2Won {
+		problems = append(problems, ValidationProblem{Err: ErrBothPlayersWon})
+	} else if (player1Won && player1PieceCount == player2PieceCount) || (player2Won && player1PieceCount != player2PieceCount) {
+		problems = append(problems, ValidationProblem{Err: ErrPlayAfterWin})
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{problems}
+	}
+
+	switch {
+	case player1Won:
+		gameState.turn = Player1Won
+	case player2Won:
+		gameState.turn = Player2Won
+	case gameState.pieceCount() == scoreFourPegs*ScoreFourSize:
+		gameState.turn = Draw
+	case player1PieceCount == player2PieceCount:
+		gameState.turn = Player1Turn
+	default:
+		gameState.turn = Player2Turn
+	}
+
+	if player1PieceCount != player2PieceCount {
+		gameState.hash ^= scoreFour.zobristSide
+	}
+
+	return gameState, nil
+}
+
+func LoadScoreFourGame(filename string) (*ScoreFourGameState, error) {
+	gameDescriptionBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseScoreFourGame(string(gameDescriptionBytes))
+}
@@ -0,0 +1,57 @@
+package connect4
+
+import "math/bits"
+
+// ScoreFourViabilityHeuristic is ViabilityHeuristic for Score Four, scoring
+// the 76 lines through the cube in place of the board's windows. It is also
+// a GameHeuristic, so NewGameEngine can search Score Four with it.
+type ScoreFourViabilityHeuristic struct {
+	targetPlayer PlayerID
+}
+
+func NewScoreFourViabilityHeuristic(targetPlayer PlayerID) *ScoreFourViabilityHeuristic {
+	return &ScoreFourViabilityHeuristic{targetPlayer}
+}
+
+func (heuristic *ScoreFourViabilityHeuristic) Heuristic(gameState *ScoreFourGameState) float64 {
+	var viability float64
+
+	switch gameState.turn {
+	case Draw:
+		return 0.0
+	case Player1Won:
+		viability = 1.0
+	case Player2Won:
+		viability = -1.0
+	default:
+		var player1Viability int
+		var player2Viability int
+
+		for _, line := range scoreFour.lines {
+			player1PieceCount := bits.OnesCount64(gameState.pieces[0] & line)
+			player2PieceCount := bits.OnesCount64(gameState.pieces[1] & line)
+			if player2PieceCount == 0 && player1PieceCount > 0 {
+				player1Viability += viabilityScore(ScoreFourSize - player1PieceCount)
+			} else if player1PieceCount == 0 && player2PieceCount > 0 {
+				player2Viability += viabilityScore(ScoreFourSize - player2PieceCount)
+			}
+		}
+
+		viability = float64(100+player1Viability-player2Viability) / float64(200+player1Viability+player2Viability)
+	}
+
+	if heuristic.targetPlayer == Player1 {
+		return viability
+	} else {
+		return -viability
+	}
+}
+
+func (heuristic *ScoreFourViabilityHeuristic) CanScore(game Game) bool {
+	_, ok := game.(*ScoreFourGameState)
+	return ok
+}
+
+func (heuristic *ScoreFourViabilityHeuristic) ScoreGame(game Game) float64 {
+	return heuristic.Heuristic(game.(*ScoreFourGameState))
+}
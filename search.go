@@ -0,0 +1,375 @@
+package connect4
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// WinScore is the least score a search gives a forced win. Heuristics stay
+// between -1.0 and 1.0, and a win found with n plies of search depth left
+// scores WinScore + n, so quicker wins score higher and slower losses lower.
+const WinScore = 2.0
+
+// SearchResult is the outcome of a search. Score is from the point of view of
+// the player to move, and PrincipalVariation is the line of play expected
+// from Move on, which ends early at the end of the game.
+type SearchResult struct {
+	Move               Move
+	Score              float64
+	PrincipalVariation []Move
+	Depth              int
+	Nodes              int
+}
+
+// Engine searches with depth-limited negamax and alpha-beta pruning. Its
+// heuristic scores positions for player, and is negated for the opponent, so
+// one engine can search for either side. It searches whichever games its
+// heuristic can score.
+type Engine struct {
+	heuristic GameHeuristic
+	player    PlayerID
+	table     *TranspositionTable
+}
+
+// NewEngine makes an engine for GameStates.
+func NewEngine(heuristic Heuristic, player PlayerID) *Engine {
+	return NewGameEngine(gameStateHeuristic{heuristic}, player)
+}
+
+func NewGameEngine(heuristic GameHeuristic, player PlayerID) *Engine {
+	return &Engine{heuristic: heuristic, player: player}
+}
+
+// searchGame is a Game the engine can search: one it can copy, play moves on
+// without the checks and bookkeeping of MakeMove, and order moves for.
+type searchGame interface {
+	Game
+	GetTurn() Turn
+	currentPlayer() PlayerID
+	playMove(move Move)
+	unplayMove() Move
+	searchClone() searchGame
+	emptyCells() int
+	centreDistance(move Move) int
+}
+
+var (
+	_ searchGame = (*GameState)(nil)
+	_ searchGame = (*ScoreFourGameState)(nil)
+)
+
+// SetTranspositionTable has searches cache their results in table, or stop
+// caching if it is nil. Engines sharing a table must use the same heuristic
+// for the same player, or they will read each other's scores.
+func (engine *Engine) SetTranspositionTable(table *TranspositionTable) {
+	engine.table = table
+}
+
+// newSearch starts a search of game, which it copies.
+func (engine *Engine) newSearch(game Game) (*search, error) {
+	searched, ok := game.(searchGame)
+	if !ok || !engine.heuristic.CanScore(game) {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedGame, game)
+	}
+
+	if searched.IsGameOver() {
+		return nil, ErrGameOver
+	}
+
+	if engine.table != nil {
+		engine.table.NewSearch()
+	}
+
+	return &search{engine: engine, table: engine.table, game: searched.searchClone()}, nil
+}
+
+// SearchOptions limit SearchContext. MaxDepth of 0 searches as deep as the
+// board has empty cells, and TimeBudget of 0 leaves the time to the context.
+type SearchOptions struct {
+	MaxDepth   int
+	TimeBudget time.Duration
+}
+
+// stopCheckInterval is how many nodes are searched between checks for
+// cancellation, a power of two.
+const stopCheckInterval = 1024
+
+// search holds the state of one search, so an Engine can run several at once.
+type search struct {
+	engine *Engine
+	table  *TranspositionTable
+	game   searchGame
+	nodes  int
+
+	// principalVariation from the last iteration is searched first
+	principalVariation []Move
+	followingVariation bool
+
+	done    <-chan struct{}
+	stopped bool
+}
+
+// Search looks depth plies ahead of game, which is left unchanged, and
+// returns the best move for the player to move.
+func (engine *Engine) Search(game Game, depth int) (SearchResult, error) {
+	search, err := engine.newSearch(game)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	if depth < 1 {
+		depth = 1
+	}
+
+	score, principalVariation := search.negamax(depth, 0, math.Inf(-1), math.Inf(1))
+
+	return SearchResult{
+		Move:               principalVariation[0],
+		Score:              score,
+		PrincipalVariation: principalVariation,
+		Depth:              depth,
+		Nodes:              search.nodes,
+	}, nil
+}
+
+// SearchContext searches with iterative deepening, one ply deeper each time,
+// until ctx is done, the time budget or MaxDepth is reached, or a forced
+// result is found. It returns the result of the deepest search to finish, and
+// always finishes the first, so it has a move to return even if ctx is
+// already done. game is left unchanged.
+func (engine *Engine) SearchContext(ctx context.Context, game Game, options SearchOptions) (SearchResult, error) {
+	search, err := engine.newSearch(game)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	if options.TimeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.TimeBudget)
+		defer cancel()
+	}
+
+	maxDepth := options.MaxDepth
+	if maxDepth < 1 {
+		maxDepth = search.game.emptyCells()
+		if maxDepth < 1 {
+			maxDepth = 1
+		}
+	}
+
+	var result SearchResult
+	for depth := 1; depth <= maxDepth; depth++ {
+		if depth > 1 && ctx.Err() != nil {
+			break
+		}
+
+		search.followingVariation = true
+		score, principalVariation := search.negamax(depth, 0, math.Inf(-1), math.Inf(1))
+		if search.stopped {
+			break
+		}
+
+		result = SearchResult{
+			Move:               principalVariation[0],
+			Score:              score,
+			PrincipalVariation: principalVariation,
+			Depth:              depth,
+		}
+		search.principalVariation = principalVariation
+
+		if math.Abs(score) >= WinScore {
+			break
+		}
+
+		// Only later iterations can be cut short
+		search.done = ctx.Done()
+	}
+	result.Nodes = search.nodes
+
+	return result, nil
+}
+
+// stop reports whether the search has been cancelled, checking only every
+// stopCheckInterval nodes.
+func (search *search) stop() bool {
+	if !search.stopped && search.nodes&(stopCheckInterval-1) == 0 {
+		select {
+		case <-search.done:
+			search.stopped = true
+		default:
+		}
+	}
+
+	return search.stopped
+}
+
+// evaluate scores the position for the player to move.
+func (search *search) evaluate(depth int) float64 {
+	toMove := search.game.currentPlayer()
+
+	var score float64
+	switch search.game.GetTurn() {
+	case Draw:
+		return 0.0
+	case Player1Won:
+		score = WinScore + float64(depth)
+		if toMove != Player1 {
+			score = -score
+		}
+		return score
+	case Player2Won:
+		score = WinScore + float64(depth)
+		if toMove != Player2 {
+			score = -score
+		}
+		return score
+	}
+
+	score = search.engine.heuristic.ScoreGame(search.game)
+	if toMove != search.engine.player {
+		score = -score
+	}
+
+	return score
+}
+
+func (search *search) negamax(depth int, ply int, alpha float64, beta float64) (float64, []Move) {
+	search.nodes++
+	game := search.game
+
+	if search.stop() {
+		return 0, nil
+	}
+
+	if depth == 0 || game.IsGameOver() {
+		search.followingVariation = false
+		return search.evaluate(depth), nil
+	}
+
+	alphaOriginal := alpha
+	moves := orderMoves(game, game.GetPossibleMoves())
+	if search.table != nil {
+		if entry, ok := search.table.Probe(game.Hash()); ok {
+			moves = moveFirst(moves, entry.Move)
+
+			// The root always searches, so there is a move to return. Scores
+			// inside the window could be on the principal variation, which
+			// the entry can't continue, so only those outside it cut off.
+			if score, ok := entryScore(entry, depth); ok && ply > 0 {
+				if (entry.Bound != UpperBound && score >= beta) || (entry.Bound != LowerBound && score <= alpha) {
+					search.followingVariation = false
+					return score, []Move{entry.Move}
+				}
+			}
+		}
+	}
+
+	if search.followingVariation && ply < len(search.principalVariation) {
+		moves = moveFirst(moves, search.principalVariation[ply])
+	} else {
+		search.followingVariation = false
+	}
+
+	var principalVariation []Move
+	bestScore := math.Inf(-1)
+	for _, move := range moves {
+		game.playMove(move)
+		score, line := search.negamax(depth-1, ply+1, -beta, -alpha)
+		score = -score
+		game.unplayMove()
+
+		if search.stopped {
+			return 0, nil
+		}
+
+		if score > bestScore {
+			bestScore = score
+			principalVariation = append([]Move{move}, line...)
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	if search.table != nil {
+		bound := ExactBound
+		if bestScore <= alphaOriginal {
+			bound = UpperBound
+		} else if bestScore >= beta {
+			bound = LowerBound
+		}
+		search.table.Store(game.Hash(), TranspositionEntry{Score: bestScore, Depth: depth, Bound: bound, Move: principalVariation[0]})
+	}
+
+	return bestScore, principalVariation
+}
+
+// entryScore returns the score of entry for a search depth plies deep, if it
+// has searched that deep. Forced results are rescored for the shallower
+// depth, and can't be used if they are too far off to be found at it.
+func entryScore(entry TranspositionEntry, depth int) (float64, bool) {
+	if entry.Depth < depth {
+		return 0, false
+	}
+
+	score := entry.Score
+	if score >= WinScore {
+		score -= float64(entry.Depth - depth)
+		return score, score >= WinScore
+	} else if score <= -WinScore {
+		score += float64(entry.Depth - depth)
+		return score, score <= -WinScore
+	}
+
+	return score, true
+}
+
+// orderMoves sorts moves to search the centre columns first, since they are
+// in the most lines and so tend to be best, which lets alpha-beta prune more.
+func orderMoves(game searchGame, moves []Move) []Move {
+	sort.SliceStable(moves, func(i int, j int) bool {
+		return game.centreDistance(moves[i]) < game.centreDistance(moves[j])
+	})
+
+	return moves
+}
+
+// moveFirst moves first to the front of moves, if it is there.
+func moveFirst(moves []Move, first Move) []Move {
+	for i, move := range moves {
+		if move == first {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = first
+			break
+		}
+	}
+
+	return moves
+}
+
+func (gameState *GameState) searchClone() searchGame {
+	return gameState.Clone()
+}
+
+func (gameState *GameState) emptyCells() int {
+	return gameState.layout.boardMask.andNot(gameState.mask).count()
+}
+
+func (gameState *GameState) centreDistance(move Move) int {
+	return centreDistance(move.Column(), gameState.layout.rules.Width)
+}
+
+func centreDistance(x int, width int) int {
+	distance := 2*x - (width - 1)
+	if distance < 0 {
+		return -distance
+	}
+
+	return distance
+}
@@ -0,0 +1,195 @@
+package connect4
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScoreFourEngineTakesWin(t *testing.T) {
+	gameState := NewScoreFourGame()
+	// Red fills three of the bottom row, yellow plays on top of them
+	for _, peg := range [][2]int{{0, 0}, {0, 0}, {1, 0}, {1, 0}, {2, 0}, {2, 0}} {
+		if err := gameState.MakeMove(PegMove(peg[0], peg[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine := NewGameEngine(NewScoreFourViabilityHeuristic(Player1), Player1)
+	engine.SetTranspositionTable(NewTranspositionTable(1<<16, TwoTier))
+	result, err := engine.SearchContext(context.Background(), gameState, SearchOptions{MaxDepth: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Move != PegMove(3, 0) || result.Score < WinScore {
+		t.Fatalf("got %+v", result)
+	}
+	if gameState.pieceCount() != 6 {
+		t.Fatal("search changed the game")
+	}
+}
+
+func TestScoreFourEngineBlocks(t *testing.T) {
+	gameState := NewScoreFourGame()
+	for _, peg := range [][2]int{{0, 3}, {0, 0}, {3, 0}, {1, 1}, {0, 1}, {2, 2}} {
+		if err := gameState.MakeMove(PegMove(peg[0], peg[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Yellow threatens the diagonal through (3, 3)
+	result, err := NewGameEngine(NewScoreFourViabilityHeuristic(Player1), Player1).Search(gameState, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move != PegMove(3, 3) {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestEngineRejectsOtherGames(t *testing.T) {
+	if _, err := NewEngine(NewViabilityHeuristic(Player1), Player1).Search(NewScoreFourGame(), 2); !errors.Is(err, ErrUnsupportedGame) {
+		t.Errorf("searched Score Four with a GameState heuristic: %v", err)
+	}
+
+	if _, err := NewGameEngine(NewScoreFourViabilityHeuristic(Player1), Player1).Search(NewGame(), 2); !errors.Is(err, ErrUnsupportedGame) {
+		t.Errorf("searched a GameState with a Score Four heuristic: %v", err)
+	}
+}
+
+func TestSearchPrincipalVariationReachesDepth(t *testing.T) {
+	engine := NewEngine(NewViabilityHeuristic(Player1), Player1)
+	engine.SetTranspositionTable(NewTranspositionTable(1<<20, TwoTier))
+
+	gameState := NewGame()
+	for _, x := range []int{3, 3, 2, 4} {
+		result, err := engine.SearchContext(context.Background(), gameState, SearchOptions{MaxDepth: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.PrincipalVariation) != result.Depth {
+			t.Fatalf("principal variation %v at depth %d", result.PrincipalVariation, result.Depth)
+		}
+
+		if err := gameState.MakeMove(DropMove(x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func playColumns(t *testing.T, columns ...int) *GameState {
+	gameState := NewGame()
+	for _, x := range columns {
+		if err := gameState.MakeMove(DropMove(x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return gameState
+}
+
+func TestEngineTakesWin(t *testing.T) {
+	// Red has three in column 0
+	gameState := playColumns(t, 0, 1, 0, 1, 0, 2)
+
+	result, err := NewEngine(NewViabilityHeuristic(Player1), Player1).Search(gameState, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move != DropMove(0) || result.Score < WinScore {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestEngineBlocksLoss(t *testing.T) {
+	// Yellow has three along the bottom, open at column 4
+	gameState := playColumns(t, 0, 1, 0, 2, 6, 3)
+
+	result, err := NewEngine(NewViabilityHeuristic(Player1), Player1).Search(gameState, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Move != DropMove(4) {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestEngineLeavesGameUnchanged(t *testing.T) {
+	gameState := playColumns(t, 3, 3, 2, 4, 4)
+	before := gameState.String()
+	hash := gameState.Hash()
+	history := gameState.History()
+
+	engine := NewEngine(NewViabilityHeuristic(Player2), Player2)
+	engine.SetTranspositionTable(NewTranspositionTable(1<<16, TwoTier))
+	result, err := engine.Search(gameState, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gameState.String() != before || gameState.Hash() != hash || len(gameState.History()) != len(history) {
+		t.Fatal("search changed the game")
+	}
+
+	// The principal variation plays out legally from the game, starting with the move
+	if len(result.PrincipalVariation) == 0 || result.PrincipalVariation[0] != result.Move {
+		t.Fatalf("got %+v", result)
+	}
+	clone := gameState.Clone()
+	for _, move := range result.PrincipalVariation {
+		if err := clone.MakeMove(move); err != nil {
+			t.Fatalf("principal variation %v: %v", result.PrincipalVariation, err)
+		}
+	}
+}
+
+func TestEngineRejectsFinishedGame(t *testing.T) {
+	gameState := playColumns(t, 0, 1, 0, 1, 0, 1, 0)
+
+	if _, err := NewEngine(NewViabilityHeuristic(Player1), Player1).Search(gameState, 2); !errors.Is(err, ErrGameOver) {
+		t.Fatalf("searched a finished game: %v", err)
+	}
+}
+
+func TestSearchContextStopsPromptly(t *testing.T) {
+	engine := NewEngine(NewViabilityHeuristic(Player1), Player1)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, test := range []struct {
+		ctx     context.Context
+		options SearchOptions
+	}{
+		{cancelled, SearchOptions{}},
+		{context.Background(), SearchOptions{TimeBudget: 20 * time.Millisecond}},
+	} {
+		gameState := NewGame()
+		start := time.Now()
+		result, err := engine.SearchContext(test.ctx, gameState, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %s with %+v", elapsed, test.options)
+		}
+		if result.Depth < 1 || !gameState.IsValidMove(result.Move) {
+			t.Errorf("got %+v with %+v", result, test.options)
+		}
+	}
+}
+
+func TestSearchContextMaxDepth(t *testing.T) {
+	engine := NewEngine(NewViabilityHeuristic(Player1), Player1)
+	for _, maxDepth := range []int{1, 3, 5} {
+		result, err := engine.SearchContext(context.Background(), NewGame(), SearchOptions{MaxDepth: maxDepth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Depth != maxDepth || len(result.PrincipalVariation) != maxDepth {
+			t.Errorf("max depth %d: got %+v", maxDepth, result)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package connect4
+
+import "sync"
+
+// Session shares one game between goroutines. Moves are applied one at a time
+// and readers get immutable Snapshots, so they never see a half made move.
+// Subscribers are notified while the move is still being applied, so they
+// must not call back into the Session unless they use DropWhenFull or a
+// large enough buffer.
+type Session struct {
+	mutex     sync.RWMutex
+	gameState *GameState
+	snapshot  *Snapshot
+}
+
+// Snapshot is a read-only copy of a Session's game at one version.
+type Snapshot struct {
+	version   uint64
+	gameState *GameState
+}
+
+// NewSession takes ownership of gameState; it must not be used directly
+// afterwards.
+func NewSession(gameState *GameState) *Session {
+	session := &Session{gameState: gameState}
+	session.snapshot = &Snapshot{0, gameState.Clone()}
+
+	return session
+}
+
+func (session *Session) Snapshot() *Snapshot {
+	session.mutex.RLock()
+	defer session.mutex.RUnlock()
+
+	return session.snapshot
+}
+
+func (session *Session) Version() uint64 {
+	return session.Snapshot().version
+}
+
+func (session *Session) MakeMove(move Move) error {
+	return session.update(func(gameState *GameState) error {
+		return gameState.MakeMove(move)
+	})
+}
+
+// MakeMoveIfCurrent plays move only if the game is still at the given
+// version, so a move chosen from a Snapshot can't land on a different
+// position. Otherwise it returns a *MoveError wrapping ErrStaleSnapshot.
+func (session *Session) MakeMoveIfCurrent(version uint64, move Move) error {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if version != session.snapshot.version {
+		return &MoveError{move, ErrStaleSnapshot}
+	}
+
+	return session.apply(func(gameState *GameState) error {
+		return gameState.MakeMove(move)
+	})
+}
+
+func (session *Session) UndoMove() error {
+	return session.update(func(gameState *GameState) error {
+		return gameState.UndoMove()
+	})
+}
+
+func (session *Session) RedoMove() error {
+	return session.update(func(gameState *GameState) error {
+		return gameState.RedoMove()
+	})
+}
+
+func (session *Session) Resign(player PlayerID) error {
+	return session.update(func(gameState *GameState) error {
+		return gameState.Resign(player)
+	})
+}
+
+func (session *Session) Reset() {
+	session.update(func(gameState *GameState) error {
+		gameState.Reset()
+		return nil
+	})
+}
+
+func (session *Session) Subscribe(options SubscribeOptions) *Subscription {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	return session.gameState.Subscribe(options)
+}
+
+func (session *Session) update(change func(gameState *GameState) error) error {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	return session.apply(change)
+}
+
+// apply must be called with the write lock held.
+func (session *Session) apply(change func(gameState *GameState) error) error {
+	if err := change(session.gameState); err != nil {
+		return err
+	}
+
+	session.snapshot = &Snapshot{session.snapshot.version + 1, session.gameState.Clone()}
+
+	return nil
+}
+
+// Version increases by one with every change to the session's game.
+func (snapshot *Snapshot) Version() uint64 {
+	return snapshot.version
+}
+
+func (snapshot *Snapshot) GetRules() Rules {
+	return snapshot.gameState.GetRules()
+}
+
+func (snapshot *Snapshot) GetTurn() Turn {
+	return snapshot.gameState.GetTurn()
+}
+
+func (snapshot *Snapshot) IsGameOver() bool {
+	return snapshot.gameState.IsGameOver()
+}
+
+func (snapshot *Snapshot) IsValidMove(move Move) bool {
+	return snapshot.gameState.IsValidMove(move)
+}
+
+func (snapshot *Snapshot) GetPossibleMoves() []Move {
+	return snapshot.gameState.GetPossibleMoves()
+}
+
+func (snapshot *Snapshot) GetBoard() *Board {
+	return snapshot.gameState.GetBoard()
+}
+
+func (snapshot *Snapshot) History() []Move {
+	return snapshot.gameState.History()
+}
+
+func (snapshot *Snapshot) WinningLines() []WinningLine {
+	return snapshot.gameState.WinningLines()
+}
+
+func (snapshot *Snapshot) Hash() uint64 {
+	return snapshot.gameState.Hash()
+}
+
+func (snapshot *Snapshot) Key() uint64 {
+	return snapshot.gameState.Key()
+}
+
+func (snapshot *Snapshot) String() string {
+	return snapshot.gameState.String()
+}
+
+// GameState returns a private, modifiable copy of the snapshot's game, for
+// example to search from.
+func (snapshot *Snapshot) GameState() *GameState {
+	return snapshot.gameState.Clone()
+}
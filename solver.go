@@ -0,0 +1,385 @@
+package connect4
+
+import "fmt"
+
+/* Solver scores follow the usual convention for Connect Four solvers, from
+ * the point of view of the player to move: 0 for a draw, and for a win one
+ * more than half the empty cells left once the winning piece is played,
+ * rounded down, so quicker wins score higher. A loss is the negative of the
+ * opponent's win. On the empty 7x6 board a win with the last of 21 pieces
+ * scores 1, and a win with the fourth scores 18.
+ */
+
+// SolveResult is the outcome of a solve. Plies is how many more moves the
+// game lasts when the winner wins as quickly and the loser loses as slowly as
+// they can. BestMoves are all the moves that keep the score. Weak results
+// only tell a win, draw or loss apart, with a Score of 1, 0 or -1 and no
+// Plies. Their BestMoves are one move that keeps a win or draw, or every
+// legal move in a loss, since there is nothing to tell them apart by.
+type SolveResult struct {
+	Score     int
+	Plies     int
+	BestMoves []Move
+	Nodes     uint64
+	Weak      bool
+}
+
+func (result SolveResult) IsWin() bool {
+	return result.Score > 0
+}
+
+func (result SolveResult) IsLoss() bool {
+	return result.Score < 0
+}
+
+func (result SolveResult) IsDraw() bool {
+	return result.Score == 0
+}
+
+// Solver finds the result of a game with perfect play from both sides, by
+// alpha-beta search to the end of the game. A Solver runs one solve at a
+// time; its table may be shared with other Solvers, but not with an Engine,
+// whose scores mean something else.
+type Solver struct {
+	table *TranspositionTable
+	nodes uint64
+
+	layout   *layout
+	current  bitboard
+	opponent bitboard
+	mask     bitboard
+	heights  []int
+	columns  []int
+	piece    Piece
+	hash     uint64
+	empty    int
+}
+
+// NewSolver makes a solver caching its results in table. Solving anything
+// but the end of a game needs one, the bigger the better.
+func NewSolver(table *TranspositionTable) *Solver {
+	return &Solver{table: table}
+}
+
+func (solver *Solver) reset(gameState *GameState) error {
+	rules := gameState.layout.rules
+	if rules.PopOut || rules.Misere || rules.GravityFree {
+		return fmt.Errorf("%w: %s can't be solved", ErrInvalidRules, rules)
+	}
+
+	if gameState.IsGameOver() {
+		return ErrGameOver
+	}
+
+	solver.layout = gameState.layout
+	solver.current = gameState.current
+	solver.opponent = gameState.getPlayerPieces(gameState.opponentPiece())
+	solver.mask = gameState.mask
+	solver.heights = make([]int, len(gameState.heights))
+	copy(solver.heights, gameState.heights)
+	solver.columns = centreOrder(rules.Width)
+	solver.piece = gameState.currentPiece
+	solver.hash = gameState.hash
+	solver.empty = solver.layout.boardMask.andNot(solver.mask).count()
+	solver.nodes = 0
+
+	return nil
+}
+
+// Solve finds the exact score of gameState and every move that achieves it.
+func (solver *Solver) Solve(gameState *GameState) (SolveResult, error) {
+	if err := solver.reset(gameState); err != nil {
+		return SolveResult{}, err
+	}
+
+	score := solver.solve(-solver.empty/2, (solver.empty+1)/2)
+	result := SolveResult{
+		Score:     score,
+		Plies:     solver.plies(score),
+		BestMoves: solver.movesScoring(score, false),
+		Nodes:     solver.nodes,
+	}
+
+	return result, nil
+}
+
+// WeakSolve finds only whether gameState is a win, draw or loss, with at most
+// two null-window searches around zero. It is often quicker than Solve, but
+// not always, as those searches can't use the tighter bounds of the exact
+// score. It shares the table with Solve, so each helps the other.
+func (solver *Solver) WeakSolve(gameState *GameState) (SolveResult, error) {
+	if err := solver.reset(gameState); err != nil {
+		return SolveResult{}, err
+	}
+
+	score := solver.solve(-1, 1)
+	if score > 0 {
+		score = 1
+	} else if score < 0 {
+		score = -1
+	}
+
+	var bestMoves []Move
+	if score < 0 {
+		bestMoves = solver.legalMoves()
+	} else {
+		bestMoves = solver.movesScoring(score, true)
+	}
+
+	result := SolveResult{
+		Score:     score,
+		BestMoves: bestMoves,
+		Nodes:     solver.nodes,
+		Weak:      true,
+	}
+
+	return result, nil
+}
+
+// solve narrows the score down between min and max with null-window
+// searches, which prune far more than a full window. A score below min comes
+// back as min, and one above max as max or more.
+func (solver *Solver) solve(min int, max int) int {
+	if solver.canWinNext() {
+		return (solver.empty + 1) / 2
+	}
+
+	for min < max {
+		// Try nearer zero first, where the searches are quickest
+		middle := min + (max-min)/2
+		if middle <= 0 && min/2 < middle {
+			middle = min / 2
+		} else if middle >= 0 && max/2 > middle {
+			middle = max / 2
+		}
+
+		if score := solver.negamax(middle, middle+1); score <= middle {
+			max = score
+		} else {
+			min = score
+		}
+	}
+
+	return min
+}
+
+// legalMoves returns the moves from the position, from the centre out.
+func (solver *Solver) legalMoves() []Move {
+	var moves []Move
+	for _, x := range solver.columns {
+		if solver.heights[x] < solver.layout.rules.Height {
+			moves = append(moves, DropMove(x))
+		}
+	}
+
+	return moves
+}
+
+// movesScoring returns the moves from the position after which the player
+// to move can still get score or better, or only the first it finds.
+func (solver *Solver) movesScoring(score int, first bool) []Move {
+	var moves []Move
+	for _, x := range solver.columns {
+		if solver.heights[x] >= solver.layout.rules.Height {
+			continue
+		}
+
+		cell := solver.layout.cellMask(x, solver.heights[x])
+		if solver.layout.winningCells(solver.current, solver.mask).intersects(cell) {
+			if (solver.empty+1)/2 >= score {
+				moves = append(moves, DropMove(x))
+				if first {
+					break
+				}
+			}
+			continue
+		}
+
+		solver.play(x)
+		var childScore int
+		if solver.canWinNext() {
+			childScore = (solver.empty + 1) / 2
+		} else {
+			childScore = solver.negamax(-score, -score+1)
+		}
+		solver.undo(x)
+
+		if -childScore >= score {
+			moves = append(moves, DropMove(x))
+			if first {
+				break
+			}
+		}
+	}
+
+	return moves
+}
+
+// plies converts a score for the player to move into the length of the game.
+func (solver *Solver) plies(score int) int {
+	switch {
+	case score > 0:
+		// The winner's last piece is their (empty+3)/2 - score'th
+		return 2*((solver.empty+3)/2-score) - 1
+	case score < 0:
+		return 2 * ((solver.empty+2)/2 + score)
+	default:
+		return solver.empty
+	}
+}
+
+func (solver *Solver) play(x int) {
+	row := solver.heights[x]
+	cell := solver.layout.cellMask(x, row)
+
+	solver.current = solver.current.or(cell)
+	solver.mask = solver.mask.or(cell)
+	solver.current, solver.opponent = solver.opponent, solver.current
+	solver.heights[x]++
+	solver.hash ^= solver.layout.zobristPiece(solver.piece, x, row) ^ solver.layout.zobristSide
+	solver.piece = Player1Piece + Player2Piece - solver.piece
+	solver.empty--
+}
+
+func (solver *Solver) undo(x int) {
+	solver.heights[x]--
+	row := solver.heights[x]
+	cell := solver.layout.cellMask(x, row)
+
+	solver.piece = Player1Piece + Player2Piece - solver.piece
+	solver.hash ^= solver.layout.zobristPiece(solver.piece, x, row) ^ solver.layout.zobristSide
+	solver.current, solver.opponent = solver.opponent, solver.current
+	solver.current = solver.current.andNot(cell)
+	solver.mask = solver.mask.andNot(cell)
+	solver.empty++
+}
+
+func (solver *Solver) canWinNext() bool {
+	return solver.layout.winningCells(solver.current, solver.mask).intersects(solver.layout.playableCells(solver.mask))
+}
+
+// nonLosingMoves returns the playable cells that don't let the opponent win
+// straight away, which is none if they have two wins to block.
+func (solver *Solver) nonLosingMoves() bitboard {
+	possible := solver.layout.playableCells(solver.mask)
+	opponentWins := solver.layout.winningCells(solver.opponent, solver.mask)
+
+	if forced := possible.and(opponentWins); !forced.isZero() {
+		if forced.count() > 1 {
+			return bitboard{}
+		}
+		possible = forced
+	}
+
+	// Nor play under a cell that would win for the opponent
+	return possible.andNot(opponentWins.shr(1))
+}
+
+// centreOrder returns the columns from the centre out.
+func centreOrder(width int) []int {
+	columns := make([]int, width)
+	for i := range columns {
+		columns[i] = width/2 + (1-2*(i%2))*(i+1)/2
+	}
+
+	return columns
+}
+
+// orderedMoves returns the columns of moves, most promising first: those
+// making the most new threats, then those nearest the centre.
+func (solver *Solver) orderedMoves(moves bitboard) []int {
+	var columns []int
+	var threats []int
+	for _, x := range solver.columns {
+		if solver.heights[x] >= solver.layout.rules.Height {
+			continue
+		}
+
+		cell := solver.layout.cellMask(x, solver.heights[x])
+		if !moves.intersects(cell) {
+			continue
+		}
+
+		count := solver.layout.winningCells(solver.current.or(cell), solver.mask.or(cell)).count()
+
+		// Insertion sort keeps the centre first among equals
+		i := len(columns)
+		columns = append(columns, x)
+		threats = append(threats, count)
+		for ; i > 0 && threats[i-1] < count; i-- {
+			columns[i], threats[i] = columns[i-1], threats[i-1]
+		}
+		columns[i], threats[i] = x, count
+	}
+
+	return columns
+}
+
+// negamax returns the score within alpha and beta, or a bound outside them
+// on the same side as the true score. The player to move mustn't be able to
+// win straight away.
+func (solver *Solver) negamax(alpha int, beta int) int {
+	solver.nodes++
+
+	moves := solver.nonLosingMoves()
+	if moves.isZero() {
+		return -solver.empty / 2
+	}
+
+	// With two cells left nobody can win, as neither can win next move
+	if solver.empty <= 2 {
+		return 0
+	}
+
+	// The opponent can't win next move, nor us this move
+	if min := -(solver.empty - 2) / 2; alpha < min {
+		alpha = min
+		if alpha >= beta {
+			return alpha
+		}
+	}
+	if max := (solver.empty - 1) / 2; beta > max {
+		beta = max
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	if solver.table != nil {
+		if entry, ok := solver.table.Probe(solver.hash); ok {
+			score := int(entry.Score)
+			if entry.Bound == UpperBound && beta > score {
+				beta = score
+			} else if entry.Bound == LowerBound && alpha < score {
+				alpha = score
+			}
+			if alpha >= beta {
+				return score
+			}
+		}
+	}
+
+	for _, x := range solver.orderedMoves(moves) {
+		solver.play(x)
+		score := -solver.negamax(-beta, -alpha)
+		solver.undo(x)
+
+		if score >= beta {
+			solver.store(score, LowerBound, x)
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	solver.store(alpha, UpperBound, 0)
+
+	return alpha
+}
+
+func (solver *Solver) store(score int, bound Bound, x int) {
+	if solver.table != nil {
+		solver.table.Store(solver.hash, TranspositionEntry{Score: float64(score), Depth: solver.empty, Bound: bound, Move: DropMove(x)})
+	}
+}
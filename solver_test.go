@@ -0,0 +1,210 @@
+package connect4
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// bruteForceScores scores every move of gameState by searching the whole
+// game tree, in the Solver's convention.
+func bruteForceScores(gameState *GameState, memo map[uint64]int) map[Move]int {
+	empty := gameState.layout.boardMask.andNot(gameState.mask).count()
+	scores := make(map[Move]int)
+	for _, move := range gameState.GetPossibleMoves() {
+		gameState.playMove(move)
+		switch gameState.turn {
+		case Player1Won, Player2Won:
+			scores[move] = (empty + 1) / 2
+		case Draw:
+			scores[move] = 0
+		default:
+			scores[move] = -bruteForceScore(gameState, memo)
+		}
+		gameState.unplayMove()
+	}
+
+	return scores
+}
+
+func bruteForceScore(gameState *GameState, memo map[uint64]int) int {
+	if score, ok := memo[gameState.hash]; ok {
+		return score
+	}
+
+	best := -gameState.layout.boardMask.count()
+	for _, score := range bruteForceScores(gameState, memo) {
+		if score > best {
+			best = score
+		}
+	}
+	memo[gameState.hash] = best
+
+	return best
+}
+
+func randomPosition(t *testing.T, random *rand.Rand, rules Rules, moves int) *GameState {
+	gameState, err := NewGameWithRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < moves && !gameState.IsGameOver(); i++ {
+		possibleMoves := gameState.GetPossibleMoves()
+		if err := gameState.MakeMove(possibleMoves[random.Intn(len(possibleMoves))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return gameState
+}
+
+var solverTestRules = []Rules{
+	{Width: 4, Height: 4, ConnectLength: 3},
+	{Width: 5, Height: 4, ConnectLength: 4},
+	{Width: 5, Height: 4, ConnectLength: 3, Cylinder: true},
+}
+
+func TestSolveMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for _, rules := range solverTestRules {
+		solver := NewSolver(NewTranspositionTable(1<<20, TwoTier))
+		memo := make(map[uint64]int)
+
+		for i := 0; i < 40; i++ {
+			gameState := randomPosition(t, random, rules, random.Intn(rules.Width*rules.Height))
+			if gameState.IsGameOver() {
+				continue
+			}
+
+			result, err := solver.Solve(gameState)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scores := bruteForceScores(gameState, memo)
+			best := bruteForceScore(gameState, memo)
+			if result.Score != best {
+				t.Fatalf("%s: score %d, expected %d\n%s", rules, result.Score, best, gameState)
+			}
+
+			bestCount := 0
+			for _, score := range scores {
+				if score == best {
+					bestCount++
+				}
+			}
+			if len(result.BestMoves) != bestCount {
+				t.Fatalf("%s: best moves %v, expected %d of them\n%s", rules, result.BestMoves, bestCount, gameState)
+			}
+			for _, move := range result.BestMoves {
+				if scores[move] != best {
+					t.Fatalf("%s: best move %s scores %d, expected %d\n%s", rules, move, scores[move], best, gameState)
+				}
+			}
+		}
+	}
+}
+
+func TestSolvePlies(t *testing.T) {
+	solver := NewSolver(NewTranspositionTable(1<<20, TwoTier))
+
+	// Player 1 wins next move, and loses the move after if they don't
+	gameState, err := ParseGameWithOptions(`
++---+---+---+---+---+---+---+
+|   |   |   |   |   |   |   |
++---+---+---+---+---+---+---+
+|   |   |   |   |   |   |   |
++---+---+---+---+---+---+---+
+|   |   |   |   |   |   |   |
++---+---+---+---+---+---+---+
+| R |   |   |   |   |   |   |
++---+---+---+---+---+---+---+
+| R | Y |   |   |   |   |   |
++---+---+---+---+---+---+---+
+| R | Y | Y |   |   |   |   |
++---+---+---+---+---+---+---+
+`, ParseOptions{Rules: StandardRules})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := solver.Solve(gameState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 18 || result.Plies != 1 || len(result.BestMoves) != 1 || result.BestMoves[0] != DropMove(0) {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func TestSolveRejectsUnsupportedRules(t *testing.T) {
+	gameState, err := NewGameWithRules(PopOutRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewSolver(nil).Solve(gameState); err == nil {
+		t.Fatal("solved a PopOut game")
+	}
+}
+
+func TestWeakSolveMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+	for _, rules := range solverTestRules {
+		solver := NewSolver(NewTranspositionTable(1<<20, TwoTier))
+		memo := make(map[uint64]int)
+
+		for i := 0; i < 40; i++ {
+			gameState := randomPosition(t, random, rules, random.Intn(rules.Width*rules.Height))
+			if gameState.IsGameOver() {
+				continue
+			}
+
+			result, err := solver.WeakSolve(gameState)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scores := bruteForceScores(gameState, memo)
+			best := sign(bruteForceScore(gameState, memo))
+			if result.Score != best {
+				t.Fatalf("%s: score %d, expected %d\n%s", rules, result.Score, best, gameState)
+			}
+
+			if best < 0 {
+				if len(result.BestMoves) != len(scores) {
+					t.Fatalf("%s: best moves %v in a loss, expected all %d\n%s", rules, result.BestMoves, len(scores), gameState)
+				}
+			} else if len(result.BestMoves) != 1 || sign(scores[result.BestMoves[0]]) != best {
+				t.Fatalf("%s: best moves %v don't keep %d\n%s", rules, result.BestMoves, best, gameState)
+			}
+		}
+	}
+}
+
+func TestWeakSolveLoss(t *testing.T) {
+	gameState := NewGame()
+	for _, x := range []int{3, 3, 4, 4, 3, 3, 2} {
+		if err := gameState.MakeMove(DropMove(x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := NewSolver(NewTranspositionTable(1<<22, TwoTier)).WeakSolve(gameState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsLoss() || len(result.BestMoves) != 7 {
+		t.Fatalf("got %+v", result)
+	}
+}
+
+func sign(score int) int {
+	if score > 0 {
+		return 1
+	} else if score < 0 {
+		return -1
+	}
+
+	return 0
+}
@@ -0,0 +1,244 @@
+package connect4
+
+// Connect four is symmetric around the centre column, so a position and its
+// mirror image have the same value with mirrored moves. The Canonical* methods
+// pick one orientation for both so caches and books can share entries.
+// Cylinder boards can also be rotated any number of columns, see Symmetry.
+
+// Symmetry maps a position onto an equivalent one: a mirror around the centre
+// column if Mirrored, then a rotation of Rotation columns to the right, which
+// only cylinder boards allow.
+type Symmetry struct {
+	Rotation int
+	Mirrored bool
+}
+
+// Symmetries lists the symmetries of boards with these rules, starting with
+// the identity.
+func (rules Rules) Symmetries() []Symmetry {
+	rotations := 1
+	if rules.Cylinder {
+		rotations = rules.Width
+	}
+
+	var symmetries []Symmetry
+	for _, mirrored := range []bool{false, true} {
+		for rotation := 0; rotation < rotations; rotation++ {
+			symmetries = append(symmetries, Symmetry{rotation, mirrored})
+		}
+	}
+
+	return symmetries
+}
+
+// Inverse returns the symmetry that undoes this one on a board width columns
+// wide.
+func (symmetry Symmetry) Inverse(width int) Symmetry {
+	// Mirroring then rotating is its own inverse
+	if symmetry.Mirrored {
+		return symmetry
+	}
+
+	return Symmetry{(width - symmetry.Rotation) % width, false}
+}
+
+// column returns where column x goes on a board width columns wide.
+func (symmetry Symmetry) column(x int, width int) int {
+	if symmetry.Mirrored {
+		x = width - 1 - x
+	}
+
+	return (x + symmetry.Rotation) % width
+}
+
+func (rules Rules) TransformMove(move Move, symmetry Symmetry) Move {
+	if move.IsCell() {
+		return CellMove(Cell{symmetry.column(move.Column(), rules.Width), move.Cell().Y})
+	}
+
+	if move.IsPop() {
+		return PopMove(symmetry.column(move.Column(), rules.Width))
+	}
+
+	return DropMove(symmetry.column(move.Column(), rules.Width))
+}
+
+func (board Board) Transform(symmetry Symmetry) *Board {
+	transformed := NewBoard(board.Width(), board.Height())
+
+	for y := 0; y < board.Height(); y++ {
+		for x := 0; x < board.Width(); x++ {
+			(*transformed)[y][symmetry.column(x, board.Width())] = board[y][x]
+		}
+	}
+
+	return transformed
+}
+
+func (board Board) Mirror() *Board {
+	mirror := NewBoard(board.Width(), board.Height())
+
+	for y := 0; y < board.Height(); y++ {
+		for x := 0; x < board.Width(); x++ {
+			(*mirror)[y][board.Width()-1-x] = board[y][x]
+		}
+	}
+
+	return mirror
+}
+
+func (rules Rules) MirrorMove(move Move) Move {
+	if move.IsCell() {
+		return CellMove(Cell{rules.Width - 1 - move.Column(), move.Cell().Y})
+	}
+
+	if move.IsPop() {
+		return PopMove(rules.Width - 1 - move.Column())
+	}
+
+	return DropMove(rules.Width - 1 - move.Column())
+}
+
+func (gameState *GameState) MirrorMove(move Move) Move {
+	return gameState.layout.rules.MirrorMove(move)
+}
+
+func (layout *layout) mirror(position bitboard) bitboard {
+	return layout.transform(position, Symmetry{Mirrored: true})
+}
+
+func (layout *layout) transform(position bitboard, symmetry Symmetry) bitboard {
+	var transformed bitboard
+
+	for x := 0; x < layout.rules.Width; x++ {
+		column := position.and(layout.columnMask(x))
+		newX := symmetry.column(x, layout.rules.Width)
+		if newX > x {
+			transformed = transformed.or(column.shl(uint(newX-x) * layout.columnStride))
+		} else {
+			transformed = transformed.or(column.shr(uint(x-newX) * layout.columnStride))
+		}
+	}
+
+	return transformed
+}
+
+// Mirror returns a copy of the game reflected around the centre column,
+// including its move history.
+func (gameState *GameState) Mirror() *GameState {
+	mirror := gameState.Clone()
+	layout := gameState.layout
+
+	mirror.current = layout.mirror(gameState.current)
+	mirror.mask = layout.mirror(gameState.mask)
+	mirror.neutral = layout.mirror(gameState.neutral)
+	mirror.hash, mirror.mirrorHash = gameState.mirrorHash, gameState.hash
+	for x := range gameState.heights {
+		mirror.heights[layout.rules.Width-1-x] = gameState.heights[x]
+	}
+	for i := range mirror.history {
+		mirror.history[i].move = gameState.MirrorMove(mirror.history[i].move)
+	}
+	for i := range mirror.redoMoves {
+		mirror.redoMoves[i] = gameState.MirrorMove(mirror.redoMoves[i])
+	}
+
+	if mirror.positionCounts != nil {
+		mirror.replayPositionCounts()
+	}
+
+	return mirror
+}
+
+// Transform returns a copy of the game with symmetry applied, including its
+// move history.
+func (gameState *GameState) Transform(symmetry Symmetry) *GameState {
+	transformed := gameState.Clone()
+	layout := gameState.layout
+
+	transformed.current = layout.transform(gameState.current, symmetry)
+	transformed.mask = layout.transform(gameState.mask, symmetry)
+	transformed.neutral = layout.transform(gameState.neutral, symmetry)
+	for x := range gameState.heights {
+		transformed.heights[symmetry.column(x, layout.rules.Width)] = gameState.heights[x]
+	}
+	for i := range transformed.history {
+		transformed.history[i].move = layout.rules.TransformMove(transformed.history[i].move, symmetry)
+	}
+	for i := range transformed.redoMoves {
+		transformed.redoMoves[i] = layout.rules.TransformMove(transformed.redoMoves[i], symmetry)
+	}
+
+	player1Pieces := transformed.getPlayerPieces(Player1Piece)
+	transformed.hash = layout.positionHash(player1Pieces, transformed.mask, transformed.neutral, transformed.currentPiece)
+	transformed.mirrorHash = layout.positionHash(layout.mirror(player1Pieces), layout.mirror(transformed.mask), layout.mirror(transformed.neutral), transformed.currentPiece)
+
+	if transformed.positionCounts != nil {
+		transformed.replayPositionCounts()
+	}
+
+	return transformed
+}
+
+// replayPositionCounts rebuilds the repetition counts by taking the game back
+// to its start and replaying it, for when the hashes have changed wholesale.
+func (gameState *GameState) replayPositionCounts() {
+	replay := gameState.Clone()
+	for len(replay.history) > 0 {
+		replay.unplayMove()
+	}
+
+	replay.initPositionCounts()
+	for _, record := range gameState.history {
+		replay.playMove(record.move)
+	}
+
+	gameState.positionCounts = replay.positionCounts
+}
+
+// MirrorKey returns Key for the mirror image of the position.
+func (gameState *GameState) MirrorKey() uint64 {
+	layout := gameState.layout
+	return layout.positionKey(layout.mirror(gameState.getPlayerPieces(Player1Piece)), layout.mirror(gameState.mask), layout.mirror(gameState.neutral), gameState.keySide())
+}
+
+// CanonicalKey returns the smaller of Key and MirrorKey, and whether that is
+// the mirror image. If it is, moves for the canonical position must be passed
+// through MirrorMove to apply to this one.
+func (gameState *GameState) CanonicalKey() (uint64, bool) {
+	key := gameState.Key()
+	mirrorKey := gameState.MirrorKey()
+	if mirrorKey < key {
+		return mirrorKey, true
+	}
+
+	return key, false
+}
+
+// CanonicalSymmetry returns the smallest Key over every symmetry of the rules,
+// including rotations on a cylinder, and the symmetry that gives it. Moves for
+// the canonical position apply to this one through the symmetry's Inverse.
+func (gameState *GameState) CanonicalSymmetry() (uint64, Symmetry) {
+	layout := gameState.layout
+	player1Pieces := gameState.getPlayerPieces(Player1Piece)
+
+	key := gameState.Key()
+	var canonical Symmetry
+	for _, symmetry := range layout.rules.Symmetries()[1:] {
+		symmetryKey := layout.positionKey(layout.transform(player1Pieces, symmetry), layout.transform(gameState.mask, symmetry), layout.transform(gameState.neutral, symmetry), gameState.keySide())
+		if symmetryKey < key {
+			key, canonical = symmetryKey, symmetry
+		}
+	}
+
+	return key, canonical
+}
+
+// CanonicalHash is the Hash counterpart of CanonicalKey.
+func (gameState *GameState) CanonicalHash() (uint64, bool) {
+	if gameState.mirrorHash < gameState.hash {
+		return gameState.mirrorHash, true
+	}
+
+	return gameState.hash, false
+}
@@ -0,0 +1,165 @@
+package connect4
+
+// PlayerThreats describes the lines one player could complete, regardless of
+// whose turn it is.
+type PlayerThreats struct {
+	Player PlayerID
+	// WinningMoves win immediately when it is Player's turn.
+	WinningMoves []Move
+	// Threats are all the empty cells that would complete a line for Player,
+	// including ones that can't be played yet.
+	Threats []Cell
+	// DoubleThreatMoves create two wins the opponent can't both stop: two
+	// playable winning cells, or one with another threat directly above it.
+	// It is empty if Player already has such a double threat.
+	DoubleThreatMoves []Move
+}
+
+// ThreatAnalysis is the threat picture for both players, plus what it means
+// for the player to move.
+type ThreatAnalysis struct {
+	Player1 PlayerThreats
+	Player2 PlayerThreats
+	// ForcedBlocks are the opponent's winning moves, which the player to move
+	// must play unless they can win first. More than one means the opponent
+	// can't be stopped.
+	ForcedBlocks []Move
+	// LosingMoves fill the cell directly below one of the opponent's threats,
+	// letting the opponent win there next turn.
+	LosingMoves []Move
+}
+
+func (analysis *ThreatAnalysis) ForPlayer(player PlayerID) *PlayerThreats {
+	if player == Player1 {
+		return &analysis.Player1
+	}
+
+	return &analysis.Player2
+}
+
+// AnalyzeThreats reports immediate wins, forced blocks, losing moves and
+// double threats for the position. It is empty once the game is over.
+func (gameState *GameState) AnalyzeThreats() *ThreatAnalysis {
+	analysis := &ThreatAnalysis{
+		Player1: PlayerThreats{Player: Player1},
+		Player2: PlayerThreats{Player: Player2},
+	}
+	if gameState.IsGameOver() {
+		return analysis
+	}
+
+	layout := gameState.layout
+	playable := gameState.playableCells()
+
+	for _, piece := range []Piece{Player1Piece, Player2Piece} {
+		threats := analysis.ForPlayer(pieceToPlayer(piece))
+		threatCells := layout.winningCells(gameState.getPlayerPieces(piece), gameState.mask)
+
+		threats.WinningMoves = layout.cellsToMoves(threatCells.and(playable))
+		threats.Threats = layout.cellsToCells(threatCells)
+		threats.DoubleThreatMoves = layout.cellsToMoves(gameState.doubleThreatCells(piece))
+	}
+
+	opponent := analysis.ForPlayer(pieceToPlayer(gameState.opponentPiece()))
+	opponentThreats := layout.winningCells(gameState.getPlayerPieces(gameState.opponentPiece()), gameState.mask)
+	analysis.ForcedBlocks = opponent.WinningMoves
+	if !layout.rules.GravityFree {
+		analysis.LosingMoves = layout.cellsToMoves(playable.and(opponentThreats.shr(1)))
+	}
+
+	return analysis
+}
+
+// winningMoveCells returns the playable cells that complete a line for piece.
+func (gameState *GameState) winningMoveCells(piece Piece) bitboard {
+	return gameState.layout.winningCells(gameState.getPlayerPieces(piece), gameState.mask).and(gameState.playableCells())
+}
+
+func (gameState *GameState) doubleThreatCells(piece Piece) bitboard {
+	layout := gameState.layout
+	pieces := gameState.getPlayerPieces(piece)
+	playable := gameState.playableCells()
+	threats := layout.winningCells(pieces, gameState.mask)
+	immediateWins := threats.and(playable)
+
+	var cells bitboard
+	if layout.isDoubleThreat(immediateWins, threats) {
+		return cells
+	}
+
+	for x := 0; x < layout.rules.Width; x++ {
+		for row := 0; row < layout.rules.Height; row++ {
+			cell := playable.and(layout.cellMask(x, row))
+			if cell.isZero() || cell.intersects(immediateWins) {
+				continue
+			}
+
+			newPieces := pieces.or(cell)
+			newMask := gameState.mask.or(cell)
+			newPlayable := playable.andNot(cell)
+			if !layout.rules.GravityFree {
+				newPlayable = newPlayable.or(cell.shl(1).and(layout.boardMask))
+			}
+			newThreats := layout.winningCells(newPieces, newMask)
+
+			if layout.isDoubleThreat(newThreats.and(newPlayable), newThreats) {
+				cells = cells.or(cell)
+			}
+		}
+	}
+
+	return cells
+}
+
+// isDoubleThreat reports whether wins can't all be blocked: there are two of
+// them, or with gravity, blocking one lets the player win directly above it.
+func (layout *layout) isDoubleThreat(wins bitboard, threats bitboard) bool {
+	return wins.count() >= 2 || (!layout.rules.GravityFree && wins.intersects(threats.shr(1)))
+}
+
+func (gameState *GameState) opponentPiece() Piece {
+	if gameState.currentPiece == Player1Piece {
+		return Player2Piece
+	}
+
+	return Player1Piece
+}
+
+func pieceToPlayer(piece Piece) PlayerID {
+	if piece == Player1Piece {
+		return Player1
+	}
+
+	return Player2
+}
+
+func (layout *layout) cellsToMoves(cells bitboard) []Move {
+	var moves []Move
+	if layout.rules.GravityFree {
+		for _, cell := range layout.cellsToCells(cells) {
+			moves = append(moves, CellMove(cell))
+		}
+		return moves
+	}
+
+	for x := 0; x < layout.rules.Width; x++ {
+		if cells.intersects(layout.columnMask(x)) {
+			moves = append(moves, Move(x))
+		}
+	}
+
+	return moves
+}
+
+func (layout *layout) cellsToCells(cells bitboard) []Cell {
+	var result []Cell
+	for x := 0; x < layout.rules.Width; x++ {
+		for row := 0; row < layout.rules.Height; row++ {
+			if cells.intersects(layout.cellMask(x, row)) {
+				result = append(result, Cell{x, layout.rules.Height - 1 - row})
+			}
+		}
+	}
+
+	return result
+}
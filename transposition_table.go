@@ -0,0 +1,203 @@
+package connect4
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+type Bound uint8
+
+const (
+	// ExactBound scores are the true value to the entry's depth.
+	ExactBound Bound = iota
+	// LowerBound scores caused a beta cutoff, so the true value is at least the score.
+	LowerBound
+	// UpperBound scores failed low, so the true value is at most the score.
+	UpperBound
+)
+
+type ReplacementPolicy int
+
+const (
+	// AlwaysReplace overwrites whatever is in the slot.
+	AlwaysReplace ReplacementPolicy = iota
+	// DepthPreferred keeps the deeper of the two, unless the old entry is
+	// from an earlier search.
+	DepthPreferred
+	// TwoTier keeps a depth-preferred slot and an always-replace slot for
+	// each bucket, so deep results survive without locking out new ones.
+	TwoTier
+)
+
+// ways returns how many slots a bucket has under the policy.
+func (policy ReplacementPolicy) ways() int {
+	if policy == TwoTier {
+		return 2
+	}
+
+	return 1
+}
+
+// TranspositionEntry is what a search learned about a position.
+type TranspositionEntry struct {
+	Score float64
+	Depth int
+	Bound Bound
+	Move  Move
+
+	generation uint8
+}
+
+type transpositionSlot struct {
+	hash  uint64
+	used  bool
+	entry TranspositionEntry
+}
+
+// transpositionLocks is how many mutexes the buckets share, a power of two.
+const transpositionLocks = 1024
+
+// TranspositionTable caches search results by position hash in a fixed
+// amount of memory. It is safe to share between goroutines, so several
+// searches can run on one table. Hashes are only unique within one set of
+// Rules, so a table should only hold positions played under one.
+type TranspositionTable struct {
+	// Counters come first to keep them 64-bit aligned for sync/atomic
+	probes uint64
+	hits   uint64
+	stores uint64
+
+	generation uint32
+	policy     ReplacementPolicy
+	ways       int
+	bucketMask uint64
+	slots      []transpositionSlot
+	locks      [transpositionLocks]sync.Mutex
+}
+
+// TranspositionStats counts table use since it was made or last cleared.
+type TranspositionStats struct {
+	Probes uint64
+	Hits   uint64
+	Stores uint64
+}
+
+// HitRate returns the fraction of probes that found their position.
+func (stats TranspositionStats) HitRate() float64 {
+	if stats.Probes == 0 {
+		return 0.0
+	}
+
+	return float64(stats.Hits) / float64(stats.Probes)
+}
+
+// NewTranspositionTable makes a table using at most sizeBytes for its
+// entries, rounded down to a power of two buckets, with at least one. TwoTier
+// buckets hold two entries and the others one.
+func NewTranspositionTable(sizeBytes int, policy ReplacementPolicy) *TranspositionTable {
+	ways := policy.ways()
+	bucketSize := ways * int(unsafe.Sizeof(transpositionSlot{}))
+
+	bucketCount := 1
+	for bucketCount*2*bucketSize <= sizeBytes {
+		bucketCount *= 2
+	}
+
+	return &TranspositionTable{
+		policy:     policy,
+		ways:       ways,
+		bucketMask: uint64(bucketCount - 1),
+		slots:      make([]transpositionSlot, bucketCount*ways),
+	}
+}
+
+// lock locks and returns the bucket for hash, its slots in a slice.
+func (table *TranspositionTable) lock(hash uint64) ([]transpositionSlot, *sync.Mutex) {
+	index := hash & table.bucketMask
+	lock := &table.locks[index&(transpositionLocks-1)]
+	lock.Lock()
+
+	start := int(index) * table.ways
+	return table.slots[start : start+table.ways], lock
+}
+
+// Probe looks up the position with the given hash.
+func (table *TranspositionTable) Probe(hash uint64) (TranspositionEntry, bool) {
+	atomic.AddUint64(&table.probes, 1)
+
+	bucket, lock := table.lock(hash)
+	defer lock.Unlock()
+
+	for _, slot := range bucket {
+		if slot.used && slot.hash == hash {
+			atomic.AddUint64(&table.hits, 1)
+			return slot.entry, true
+		}
+	}
+
+	return TranspositionEntry{}, false
+}
+
+// Store records entry for the position with the given hash, if the
+// replacement policy lets it in.
+func (table *TranspositionTable) Store(hash uint64, entry TranspositionEntry) {
+	bucket, lock := table.lock(hash)
+	defer lock.Unlock()
+
+	entry.generation = uint8(atomic.LoadUint32(&table.generation))
+
+	slot := &bucket[0]
+	switch table.policy {
+	case DepthPreferred:
+		if !table.prefer(slot, hash, entry) {
+			return
+		}
+	case TwoTier:
+		if !table.prefer(slot, hash, entry) {
+			slot = &bucket[1]
+		} else if bucket[1].hash == hash {
+			// Don't leave a stale copy of the position in the other slot
+			bucket[1].used = false
+		}
+	}
+
+	*slot = transpositionSlot{hash, true, entry}
+	atomic.AddUint64(&table.stores, 1)
+}
+
+// prefer reports whether entry should replace what is in a depth-preferred
+// slot.
+func (table *TranspositionTable) prefer(slot *transpositionSlot, hash uint64, entry TranspositionEntry) bool {
+	return !slot.used || slot.hash == hash || slot.entry.generation != entry.generation || entry.Depth >= slot.entry.Depth
+}
+
+// NewSearch marks the entries already stored as from an earlier search, so
+// depth-preferred slots give them up to newer ones.
+func (table *TranspositionTable) NewSearch() {
+	atomic.AddUint32(&table.generation, 1)
+}
+
+// Clear empties the table and resets its statistics.
+func (table *TranspositionTable) Clear() {
+	for i := range table.locks {
+		table.locks[i].Lock()
+	}
+	for i := range table.slots {
+		table.slots[i] = transpositionSlot{}
+	}
+	atomic.StoreUint64(&table.probes, 0)
+	atomic.StoreUint64(&table.hits, 0)
+	atomic.StoreUint64(&table.stores, 0)
+	for i := range table.locks {
+		table.locks[i].Unlock()
+	}
+}
+
+func (table *TranspositionTable) Stats() TranspositionStats {
+	return TranspositionStats{
+		Probes: atomic.LoadUint64(&table.probes),
+		Hits:   atomic.LoadUint64(&table.hits),
+		Stores: atomic.LoadUint64(&table.stores),
+	}
+}
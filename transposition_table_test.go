@@ -0,0 +1,44 @@
+package connect4
+
+import "testing"
+
+func TestTranspositionTableUsesEverySlot(t *testing.T) {
+	for _, policy := range []ReplacementPolicy{AlwaysReplace, DepthPreferred, TwoTier} {
+		table := NewTranspositionTable(1<<12, policy)
+
+		// Each bucket gets as many hashes as it has slots, the later ones
+		// shallower so TwoTier keeps both
+		for hash := uint64(0); hash < uint64(len(table.slots)); hash++ {
+			table.Store(hash, TranspositionEntry{Depth: len(table.slots) - int(hash)})
+		}
+
+		for hash := uint64(0); hash < uint64(len(table.slots)); hash++ {
+			if _, ok := table.Probe(hash); !ok {
+				t.Fatalf("policy %d lost hash %d of %d", policy, hash, len(table.slots))
+			}
+		}
+	}
+}
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	for _, test := range []struct {
+		policy   ReplacementPolicy
+		deepKept bool
+		newKept  bool
+	}{
+		{AlwaysReplace, false, true},
+		{DepthPreferred, true, false},
+		{TwoTier, true, true},
+	} {
+		table := NewTranspositionTable(1, test.policy)
+		table.Store(1, TranspositionEntry{Depth: 5})
+		table.Store(2, TranspositionEntry{Depth: 1})
+
+		if _, ok := table.Probe(1); ok != test.deepKept {
+			t.Errorf("policy %d kept the deep entry: %t", test.policy, ok)
+		}
+		if _, ok := table.Probe(2); ok != test.newKept {
+			t.Errorf("policy %d kept the new entry: %t", test.policy, ok)
+		}
+	}
+}
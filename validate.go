@@ -0,0 +1,211 @@
+package connect4
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrIllegalPosition = errors.New("illegal position")
+	ErrFloatingPiece   = errors.New("piece has an empty cell below it")
+	ErrPieceCount      = errors.New("piece counts can't arise in play")
+	ErrBothPlayersWon  = errors.New("both players have a winning line")
+	ErrPlayAfterWin    = errors.New("play continued after a win")
+	ErrUnknownPiece    = errors.New("unknown piece")
+	ErrWrongTurn       = errors.New("turn doesn't match the pieces on the board")
+)
+
+// ValidationProblem is one reason a position is illegal, with the cells
+// involved in Board coordinates.
+type ValidationProblem struct {
+	Err   error
+	Cells []Cell
+}
+
+func (problem ValidationProblem) String() string {
+	if len(problem.Cells) == 0 {
+		return problem.Err.Error()
+	}
+
+	cells := make([]string, len(problem.Cells))
+	for i, cell := range problem.Cells {
+		cells[i] = fmt.Sprintf("(%d, %d)", cell.X, cell.Y)
+	}
+
+	return fmt.Sprintf("%s at %s", problem.Err, strings.Join(cells, " "))
+}
+
+// ValidationError lists every problem found with a position. It matches
+// ErrIllegalPosition and each of its problems' errors with errors.Is.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (err *ValidationError) Error() string {
+	problems := make([]string, len(err.Problems))
+	for i, problem := range err.Problems {
+		problems[i] = problem.String()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrIllegalPosition, strings.Join(problems, "; "))
+}
+
+func (err *ValidationError) Unwrap() error {
+	return ErrIllegalPosition
+}
+
+func (err *ValidationError) Is(target error) bool {
+	for _, problem := range err.Problems {
+		if errors.Is(problem.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks that the board could have been reached by legal play under
+// rules, returning a *ValidationError listing every problem found.
+func (board Board) Validate(rules Rules) error {
+	return board.validate(rules, false)
+}
+
+// ValidateSetup checks a starting position for a handicap or puzzle game,
+// which can have neutral pieces and any number of each player's pieces. It
+// still rejects floating pieces and lines for both players.
+func (board Board) ValidateSetup(rules Rules) error {
+	return board.validate(rules, true)
+}
+
+func (board Board) validate(rules Rules, setup bool) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+
+	if board.Width() != rules.Width || board.Height() != rules.Height {
+		return fmt.Errorf("%w: %dx%d board, expected %dx%d", ErrInvalidRules, board.Width(), board.Height(), rules.Width, rules.Height)
+	}
+
+	var problems []ValidationProblem
+
+	player1PieceCount := 0
+	player2PieceCount := 0
+	for y := 0; y < board.Height(); y++ {
+		for x := 0; x < board.Width(); x++ {
+			switch board[y][x] {
+			case EmptyPiece, NeutralPiece:
+			case Player1Piece:
+				player1PieceCount++
+			case Player2Piece:
+				player2PieceCount++
+			default:
+				problems = append(problems, ValidationProblem{ErrUnknownPiece, []Cell{{x, y}}})
+			}
+		}
+	}
+
+	// Unknown pieces, the only problems so far, are left out of the line checks
+	lineBoard := board
+	if len(problems) > 0 {
+		lineBoard = *board.Clone()
+		for _, problem := range problems {
+			lineBoard[problem.Cells[0].Y][problem.Cells[0].X] = EmptyPiece
+		}
+	}
+
+	// Pops take pieces away, so the counts can be anything
+	countsValid := player1PieceCount == player2PieceCount || player1PieceCount == player2PieceCount+1
+	if !countsValid && !setup && !rules.PopOut {
+		problems = append(problems, ValidationProblem{Err: fmt.Errorf("%w: %d red pieces, %d yellow pieces", ErrPieceCount, player1PieceCount, player2PieceCount)})
+	}
+
+	for x := 0; x < board.Width() && !rules.GravityFree; x++ {
+		for y := board.Height() - 2; y >= 0; y-- {
+			if board[y][x] != EmptyPiece && board[y+1][x] == EmptyPiece {
+				problems = append(problems, ValidationProblem{ErrFloatingPiece, []Cell{{x, y}}})
+			}
+		}
+	}
+
+	// A pop can leave both players with lines
+	if !rules.PopOut {
+		gameState := newGameFromBoard(rules, &lineBoard, Player1Piece, Player1Turn)
+		layout := gameState.layout
+		player1Lines := layout.findLines(gameState.getPlayerPieces(Player1Piece))
+		player2Lines := layout.findLines(gameState.getPlayerPieces(Player2Piece))
+
+		if len(player1Lines) > 0 && len(player2Lines) > 0 {
+			problems = append(problems, ValidationProblem{ErrBothPlayersWon, lineCells(append(player1Lines, player2Lines...))})
+		} else if len(player1Lines) > 0 && !setup {
+			if player1PieceCount != player2PieceCount+1 || !gameState.hasFinalMove(Player1Piece) {
+				problems = append(problems, ValidationProblem{ErrPlayAfterWin, lineCells(player1Lines)})
+			}
+		} else if len(player2Lines) > 0 && !setup {
+			if player1PieceCount != player2PieceCount || !gameState.hasFinalMove(Player2Piece) {
+				problems = append(problems, ValidationProblem{ErrPlayAfterWin, lineCells(player2Lines)})
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{problems}
+	}
+
+	return nil
+}
+
+// Validate checks the game's position as Board.Validate does, and that the
+// turn matches it.
+func (gameState *GameState) Validate() error {
+	err := gameState.GetBoard().validate(gameState.layout.rules, gameState.fromSetup)
+	if err != nil || gameState.IsGameOver() || gameState.layout.rules.PopOut || gameState.fromSetup {
+		return err
+	}
+
+	player1PieceCount := gameState.getPlayerPieces(Player1Piece).count()
+	player2PieceCount := gameState.getPlayerPieces(Player2Piece).count()
+	if (gameState.turn == Player1Turn) != (player1PieceCount == player2PieceCount) {
+		return &ValidationError{[]ValidationProblem{{Err: ErrWrongTurn}}}
+	}
+
+	return nil
+}
+
+// hasFinalMove reports whether some top piece of the winner, or any piece
+// without gravity, completes every winning line on the board, so it could
+// have been the last move played.
+func (gameState *GameState) hasFinalMove(winner Piece) bool {
+	layout := gameState.layout
+	winnerPieces := gameState.getPlayerPieces(winner)
+
+	for x, height := range gameState.heights {
+		if height == 0 {
+			continue
+		}
+
+		// Without gravity any of the winner's pieces could have been last
+		lowestRow := height - 1
+		if layout.rules.GravityFree {
+			lowestRow = 0
+		}
+
+		for row := lowestRow; row < height; row++ {
+			last := layout.cellMask(x, row)
+			if winnerPieces.intersects(last) && !layout.hasConnect(winnerPieces.andNot(last)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func lineCells(lines []WinningLine) []Cell {
+	var cells []Cell
+	for _, line := range lines {
+		cells = append(cells, line.Cells...)
+	}
+
+	return cells
+}
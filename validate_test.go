@@ -0,0 +1,43 @@
+package connect4
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateChecksLinesAlongsideOtherProblems(t *testing.T) {
+	board := *NewBoard(7, 6)
+	for y := 2; y < 6; y++ {
+		board[y][0] = Player1Piece
+		board[y][6] = Player2Piece
+	}
+	// A floating piece, and an unknown one in what would be a third line
+	board[0][3] = Player1Piece
+	board[5][1] = Piece(9)
+
+	err := board.Validate(StandardRules)
+	for _, target := range []error{ErrFloatingPiece, ErrUnknownPiece, ErrBothPlayersWon} {
+		if !errors.Is(err, target) {
+			t.Errorf("%v doesn't report %v", err, target)
+		}
+	}
+}
+
+func TestValidateChecksPlayAfterWinAlongsideOtherProblems(t *testing.T) {
+	board := *NewBoard(7, 6)
+	for x := 0; x < 4; x++ {
+		board[5][x] = Player1Piece
+	}
+	for x := 0; x < 3; x++ {
+		board[4][x] = Player2Piece
+	}
+	// Yellow played a fourth piece after red's win, floating in the air
+	board[0][6] = Player2Piece
+
+	err := board.Validate(StandardRules)
+	for _, target := range []error{ErrFloatingPiece, ErrPlayAfterWin} {
+		if !errors.Is(err, target) {
+			t.Errorf("%v doesn't report %v", err, target)
+		}
+	}
+}
@@ -8,25 +8,25 @@ func NewViabilityHeuristic(targetPlayer PlayerID) *ViabilityHeuristic {
 	return &ViabilityHeuristic{targetPlayer}
 }
 
-func (heuristic *ViabilityHeuristic) increaseViabilityScores(player1PieceCount int, player2PieceCount int, player1Viability *int, player2Viability *int) {
-	if player2PieceCount == 0 {
-		switch player1PieceCount {
-		case 1:
-			*player1Viability += 1
-		case 2:
-			*player1Viability += 5
-		case 3:
-			*player1Viability += 20
-		}
-	} else if player1PieceCount == 0 {
-		switch player2PieceCount {
-		case 1:
-			*player2Viability += 1
-		case 2:
-			*player2Viability += 5
-		case 3:
-			*player2Viability += 20
-		}
+// viabilityScore weights an uncontested window by how many pieces it still needs.
+func viabilityScore(missingPieces int) int {
+	switch missingPieces {
+	case 1:
+		return 20
+	case 2:
+		return 5
+	case 3:
+		return 1
+	}
+
+	return 0
+}
+
+func (heuristic *ViabilityHeuristic) increaseViabilityScores(connectLength int, player1PieceCount int, player2PieceCount int, player1Viability *int, player2Viability *int) {
+	if player2PieceCount == 0 && player1PieceCount > 0 {
+		*player1Viability += viabilityScore(connectLength - player1PieceCount)
+	} else if player1PieceCount == 0 && player2PieceCount > 0 {
+		*player2Viability += viabilityScore(connectLength - player2PieceCount)
 	}
 }
 
@@ -50,177 +50,25 @@ func (heuristic *ViabilityHeuristic) Heuristic(gameState *GameState) float64 {
 	var player1Viability int
 	var player2Viability int
 
-	// Check for horizontal viability
-	for y := 0; y < BoardHeight; y++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-
-		x := 0
-		for ; x < 3; x++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-		}
-
-		for ; x < BoardWidth; x++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			heuristic.increaseViabilityScores(player1PieceCount, player2PieceCount, &player1Viability, &player2Viability)
-
-			switch gameState.board[y][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
-		}
-	}
-
-	// Check for vertical viability
-	for x := 0; x < BoardWidth; x++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-		y := 0
-		for ; y < 3; y++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
+	player1Pieces := gameState.getPlayerPieces(Player1Piece)
+	player2Pieces := gameState.getPlayerPieces(Player2Piece)
+	connectLength := gameState.layout.rules.ConnectLength
+	for _, window := range gameState.layout.windows {
+		// Neutral pieces block the window for both players
+		if window.intersects(gameState.neutral) {
+			continue
 		}
 
-		for ; y < BoardHeight; y++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			heuristic.increaseViabilityScores(player1PieceCount, player2PieceCount, &player1Viability, &player2Viability)
-
-			switch gameState.board[y-3][x] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
-		}
+		heuristic.increaseViabilityScores(connectLength, player1Pieces.and(window).count(), player2Pieces.and(window).count(), &player1Viability, &player2Viability)
 	}
 
-	// Check for diagonally up viability
-	for xIndex := 4 - BoardHeight; xIndex < BoardWidth-3; xIndex++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-
-		var (
-			x int
-			y int
-		)
-		if xIndex < 0 {
-			x = 0
-			y = -xIndex
-		} else {
-			x = xIndex
-			y = 0
-		}
-
-		for i := 0; i < 3; i++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			x++
-			y++
-		}
-
-		for x < BoardWidth && y < BoardHeight {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			heuristic.increaseViabilityScores(player1PieceCount, player2PieceCount, &player1Viability, &player2Viability)
-
-			switch gameState.board[y-3][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
-
-			x++
-			y++
-		}
-	}
-
-	// Check for diagonally down viability
-	for xIndex := 4 - BoardHeight; xIndex < BoardWidth-3; xIndex++ {
-		player1PieceCount := 0
-		player2PieceCount := 0
-
-		var (
-			x int
-			y int
-		)
-		if xIndex < 0 {
-			x = 0
-			y = BoardHeight - 1 + xIndex
-		} else {
-			x = xIndex
-			y = BoardHeight - 1
-		}
-
-		for i := 0; i < 3; i++ {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			x++
-			y--
-		}
-
-		for x < BoardWidth && y >= 0 {
-			switch gameState.board[y][x] {
-			case Player1Piece:
-				player1PieceCount++
-			case Player2Piece:
-				player2PieceCount++
-			}
-
-			heuristic.increaseViabilityScores(player1PieceCount, player2PieceCount, &player1Viability, &player2Viability)
-
-			switch gameState.board[y+3][x-3] {
-			case Player1Piece:
-				player1PieceCount--
-			case Player2Piece:
-				player2PieceCount--
-			}
+	var viability float64 = float64(100+player1Viability-player2Viability) / float64(200+player1Viability+player2Viability)
 
-			x++
-			y--
-		}
+	// Under misère every line a player builds counts against them
+	if gameState.layout.rules.Misere {
+		viability = -viability
 	}
 
-	var viability float64 = float64(100+player1Viability-player2Viability) / float64(200+player1Viability+player2Viability)
-
 	if heuristic.targetPlayer == Player1 {
 		return viability
 	} else {
@@ -0,0 +1,133 @@
+package connect4
+
+type Direction int
+
+const (
+	Horizontal Direction = iota
+	Vertical
+	DiagonalUp   // Bottom left to top right
+	DiagonalDown // Top left to bottom right
+)
+
+func (direction Direction) String() string {
+	switch direction {
+	case Horizontal:
+		return "horizontal"
+	case Vertical:
+		return "vertical"
+	case DiagonalUp:
+		return "diagonal up"
+	case DiagonalDown:
+		return "diagonal down"
+	default:
+		return "unknown direction"
+	}
+}
+
+// Cell uses Board coordinates, with Y = 0 as the top row.
+type Cell struct {
+	X int
+	Y int
+}
+
+type WinningLine struct {
+	Direction Direction
+	Cells     []Cell
+}
+
+// WinningLines returns every line of the winner's pieces at least ConnectLength
+// long, so a drop that completes several lines at once reports all of them.
+// Under Misere rules they are the loser's lines, the ones that decided the
+// game. It is empty unless the game has been won.
+func (gameState *GameState) WinningLines() []WinningLine {
+	var winnerPiece, loserPiece Piece
+	switch gameState.turn {
+	case Player1Won:
+		winnerPiece, loserPiece = Player1Piece, Player2Piece
+	case Player2Won:
+		winnerPiece, loserPiece = Player2Piece, Player1Piece
+	default:
+		return nil
+	}
+
+	if gameState.layout.rules.Misere {
+		return gameState.layout.findLines(gameState.getPlayerPieces(loserPiece))
+	}
+
+	return gameState.layout.findLines(gameState.getPlayerPieces(winnerPiece))
+}
+
+// WinningCells returns the cells of all the winning lines, each cell once.
+func (gameState *GameState) WinningCells() []Cell {
+	var cells []Cell
+	seen := map[Cell]bool{}
+
+	for _, line := range gameState.WinningLines() {
+		for _, cell := range line.Cells {
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+		}
+	}
+
+	return cells
+}
+
+func (layout *layout) findLines(position bitboard) []WinningLine {
+	var lines []WinningLine
+	rules := layout.rules
+
+	// Steps are in bitboard rows, which count up from the bottom
+	steps := []struct {
+		direction Direction
+		dx        int
+		dRow      int
+	}{
+		{Horizontal, 1, 0},
+		{Vertical, 0, 1},
+		{DiagonalUp, 1, 1},
+		{DiagonalDown, 1, -1},
+	}
+
+	column := func(x int) int {
+		if rules.Cylinder {
+			return (x + rules.Width) % rules.Width
+		}
+		return x
+	}
+
+	hasPiece := func(x int, row int) bool {
+		x = column(x)
+		return x >= 0 && x < rules.Width && row >= 0 && row < rules.Height && position.intersects(layout.cellMask(x, row))
+	}
+
+	for _, step := range steps {
+		for x := 0; x < rules.Width; x++ {
+			for row := 0; row < rules.Height; row++ {
+				// A full row on a cylinder has no first piece, so start it at column 0
+				rowMask := layout.bottomMask.shl(uint(row))
+				ring := rules.Cylinder && step.dRow == 0 && position.and(rowMask) == rowMask
+
+				// Only start from the first piece of each run
+				if !hasPiece(x, row) || (hasPiece(x-step.dx, row-step.dRow) && !(ring && x == 0)) {
+					continue
+				}
+
+				var cells []Cell
+				for nextX, nextRow := x, row; hasPiece(nextX, nextRow); nextX, nextRow = nextX+step.dx, nextRow+step.dRow {
+					if ring && len(cells) == rules.Width {
+						break
+					}
+					cells = append(cells, Cell{column(nextX), rules.Height - 1 - nextRow})
+				}
+
+				if len(cells) >= rules.ConnectLength {
+					lines = append(lines, WinningLine{step.direction, cells})
+				}
+			}
+		}
+	}
+
+	return lines
+}
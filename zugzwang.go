@@ -0,0 +1,109 @@
+package connect4
+
+/* Odd/even threat analysis
+ *
+ * Rows are counted from 1 at the bottom. When the board fills up, the first
+ * player can count on getting odd row cells and the second player even row
+ * cells, so an odd threat is good for player 1 and an even threat is good for
+ * player 2. The prediction follows the usual rules of thumb, assuming an even
+ * board height as on the standard board:
+ *
+ * - A threat with an opponent threat lower in the same column never comes
+ *   into play.
+ * - Player 1 wins with a good (odd) threat, even against good threats of
+ *   player 2 in other columns.
+ * - Otherwise player 2 wins with a good (even) threat.
+ * - Otherwise player 2 keeps zugzwang control and the game is drawn.
+ */
+
+type ParityThreat struct {
+	Player PlayerID
+	Cell   Cell
+	Row    int
+	// Useful is false when the opponent has a threat lower in the same column.
+	Useful bool
+}
+
+func (threat ParityThreat) IsOdd() bool {
+	return threat.Row%2 == 1
+}
+
+// IsGood reports whether the threat is on the row parity that favours its
+// owner: odd for player 1, even for player 2.
+func (threat ParityThreat) IsGood() bool {
+	return threat.IsOdd() == (threat.Player == Player1)
+}
+
+type ZugzwangReport struct {
+	Threats []ParityThreat
+	// Prediction is Player1Won, Player2Won or Draw.
+	Prediction Turn
+}
+
+func (report *ZugzwangReport) GoodThreats(player PlayerID) []ParityThreat {
+	var threats []ParityThreat
+	for _, threat := range report.Threats {
+		if threat.Player == player && threat.Useful && threat.IsGood() {
+			threats = append(threats, threat)
+		}
+	}
+
+	return threats
+}
+
+// AnalyzeZugzwang classifies every threat on the board by owner and row
+// parity and predicts the result once the board fills up. It works on boards
+// that can't arise in play too.
+func (board Board) AnalyzeZugzwang(rules Rules) (*ZugzwangReport, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newGameFromBoard(rules, &board, Player1Piece, Player1Turn).AnalyzeZugzwang(), nil
+}
+
+// Without gravity there is no order to fill cells in, so a gravity-free game
+// gets an empty report.
+func (gameState *GameState) AnalyzeZugzwang() *ZugzwangReport {
+	layout := gameState.layout
+	report := &ZugzwangReport{Prediction: Draw}
+	if layout.rules.GravityFree {
+		return report
+	}
+
+	player1Threats := layout.winningCells(gameState.getPlayerPieces(Player1Piece), gameState.mask)
+	player2Threats := layout.winningCells(gameState.getPlayerPieces(Player2Piece), gameState.mask)
+
+	for x := 0; x < layout.rules.Width; x++ {
+		lowestPlayer1Row := layout.rules.Height
+		lowestPlayer2Row := layout.rules.Height
+		for row := layout.rules.Height - 1; row >= 0; row-- {
+			cell := layout.cellMask(x, row)
+			if player1Threats.intersects(cell) {
+				lowestPlayer1Row = row
+			}
+			if player2Threats.intersects(cell) {
+				lowestPlayer2Row = row
+			}
+		}
+
+		for row := 0; row < layout.rules.Height; row++ {
+			cell := layout.cellMask(x, row)
+			boardCell := Cell{x, layout.rules.Height - 1 - row}
+			if player1Threats.intersects(cell) {
+				report.Threats = append(report.Threats, ParityThreat{Player1, boardCell, row + 1, row <= lowestPlayer2Row})
+			}
+			if player2Threats.intersects(cell) {
+				report.Threats = append(report.Threats, ParityThreat{Player2, boardCell, row + 1, row <= lowestPlayer1Row})
+			}
+		}
+	}
+
+	if len(report.GoodThreats(Player1)) > 0 {
+		report.Prediction = Player1Won
+	} else if len(report.GoodThreats(Player2)) > 0 {
+		report.Prediction = Player2Won
+	}
+
+	return report
+}
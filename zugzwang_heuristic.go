@@ -0,0 +1,40 @@
+package connect4
+
+// ZugzwangHeuristic blends ViabilityExtendedHeuristic with the odd/even
+// threat prediction from AnalyzeZugzwang, which matters most late in the game
+// when the viability counts stop changing.
+type ZugzwangHeuristic struct {
+	targetPlayer PlayerID
+	viability    *ViabilityExtendedHeuristic
+}
+
+func NewZugzwangHeuristic(targetPlayer PlayerID) *ZugzwangHeuristic {
+	return &ZugzwangHeuristic{targetPlayer, NewViabilityExtendedHeuristic(targetPlayer)}
+}
+
+func (heuristic *ZugzwangHeuristic) Heuristic(gameState *GameState) float64 {
+	viability := heuristic.viability.Heuristic(gameState)
+	if gameState.IsGameOver() || viability >= 0.99 {
+		return viability
+	}
+
+	// The parity prediction assumes lines win and pieces fall, so it says
+	// nothing under misère or without gravity
+	if gameState.layout.rules.Misere || gameState.layout.rules.GravityFree {
+		return viability
+	}
+
+	var prediction float64
+	switch gameState.AnalyzeZugzwang().Prediction {
+	case Player1Won:
+		prediction = 1.0
+	case Player2Won:
+		prediction = -1.0
+	}
+
+	if heuristic.targetPlayer != Player1 {
+		prediction = -prediction
+	}
+
+	return 0.7*viability + 0.25*prediction
+}